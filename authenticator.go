@@ -0,0 +1,235 @@
+package camunda_client_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenRefreshBefore a default time before the token expiration when OAuth2Authenticator requests a new token
+const DefaultTokenRefreshBefore = 30 * time.Second
+
+// Authenticator sets credentials of requests to the engine
+type Authenticator interface {
+	// Authenticate sets credentials of the request, e.g. the Authorization header
+	Authenticate(req *http.Request) error
+}
+
+// RefreshableAuthenticator an authenticator with renewable credentials.
+// The client invalidates the credentials and retries the request once if the engine responds 401 Unauthorized
+type RefreshableAuthenticator interface {
+	Authenticator
+	// Invalidate drops cached credentials, the next Authenticate call obtains new ones
+	Invalidate()
+}
+
+// NoAuthenticator sends requests without credentials
+type NoAuthenticator struct{}
+
+// Authenticate does nothing
+func (NoAuthenticator) Authenticate(*http.Request) error {
+	return nil
+}
+
+// BasicAuthenticator authenticates requests by the HTTP basic authentication
+type BasicAuthenticator struct {
+	User     string
+	Password string
+}
+
+// Authenticate sets the basic Authorization header
+func (a *BasicAuthenticator) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.User, a.Password)
+	return nil
+}
+
+// BearerTokenAuthenticator authenticates requests by a static bearer token
+type BearerTokenAuthenticator struct {
+	Token string
+}
+
+// Authenticate sets the bearer Authorization header
+func (a *BearerTokenAuthenticator) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// OAuth2Options options for OAuth2Authenticator
+type OAuth2Options struct {
+	// Mandatory. Url of the token endpoint of the authorization server
+	TokenUrl string
+	// Mandatory. The client id
+	ClientId string
+	// Mandatory. The client secret
+	ClientSecret string
+	// scopes to request
+	Scopes []string
+	// additional parameters of the token request, e.g. `audience`
+	EndpointParams map[string]string
+	// send the client credentials in the request body instead of the basic Authorization header
+	CredentialsInBody bool
+	// time before the token expiration when a new token is requested, at most a half of the token lifetime
+	// (default: 30 seconds)
+	RefreshBefore time.Duration
+	// http client for token requests (default: client with 60 seconds timeout)
+	HttpClient *http.Client
+}
+
+// OAuth2Authenticator authenticates requests by bearer tokens obtained with the OAuth2 client credentials grant.
+// Tokens are cached and renewed before the expiration, one token request is sent at a time
+type OAuth2Authenticator struct {
+	options OAuth2Options
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+	refreshAt time.Time
+	// refreshing is closed when the pending token request is finished, nil if no request is pending
+	refreshing chan struct{}
+}
+
+// NewOAuth2Authenticator a create new instance OAuth2Authenticator
+func NewOAuth2Authenticator(options OAuth2Options) *OAuth2Authenticator {
+	if options.RefreshBefore <= 0 {
+		options.RefreshBefore = DefaultTokenRefreshBefore
+	}
+
+	if options.HttpClient == nil {
+		options.HttpClient = &http.Client{
+			Timeout: time.Second * DefaultTimeoutSec,
+		}
+	}
+
+	return &OAuth2Authenticator{options: options}
+}
+
+// Authenticate sets the bearer Authorization header, it requests a new token if the cached one expires soon.
+// Concurrent requests wait for the pending token request or use the cached token while it is still valid
+func (a *OAuth2Authenticator) Authenticate(req *http.Request) error {
+	for {
+		a.mu.Lock()
+		now := time.Now()
+		valid := a.token != "" && now.Before(a.expiresAt)
+		if valid && (now.Before(a.refreshAt) || a.refreshing != nil) {
+			token := a.token
+			a.mu.Unlock()
+			req.Header.Set("Authorization", "Bearer "+token)
+			return nil
+		}
+
+		if refreshing := a.refreshing; refreshing != nil {
+			a.mu.Unlock()
+			select {
+			case <-refreshing:
+				continue
+			case <-req.Context().Done():
+				return req.Context().Err()
+			}
+		}
+
+		refreshing := make(chan struct{})
+		a.refreshing = refreshing
+		a.mu.Unlock()
+
+		token, lifetime, err := a.requestToken(req)
+
+		a.mu.Lock()
+		a.refreshing = nil
+		close(refreshing)
+		if err != nil {
+			a.mu.Unlock()
+			return err
+		}
+		now = time.Now()
+		a.token = token
+		a.expiresAt = now.Add(lifetime)
+		a.refreshAt = a.expiresAt.Add(-a.refreshBefore(lifetime))
+		a.mu.Unlock()
+
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// refreshBefore returns the time before the expiration of a token of the lifetime when a new token is requested,
+// it is at most a half of the lifetime, so short-lived tokens are not requested for every request
+func (a *OAuth2Authenticator) refreshBefore(lifetime time.Duration) time.Duration {
+	if a.options.RefreshBefore > lifetime/2 {
+		return lifetime / 2
+	}
+
+	return a.options.RefreshBefore
+}
+
+// Invalidate drops the cached token
+func (a *OAuth2Authenticator) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.token = ""
+}
+
+// resOAuth2Token a response of the token endpoint
+type resOAuth2Token struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken requests a new token, it returns the token and its lifetime
+func (a *OAuth2Authenticator) requestToken(req *http.Request) (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(a.options.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.options.Scopes, " "))
+	}
+	for k, v := range a.options.EndpointParams {
+		form.Set(k, v)
+	}
+	if a.options.CredentialsInBody {
+		form.Set("client_id", a.options.ClientId)
+		form.Set("client_secret", a.options.ClientSecret)
+	}
+
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, a.options.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed create token request: %w", err)
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if !a.options.CredentialsInBody {
+		tokenReq.SetBasicAuth(url.QueryEscape(a.options.ClientId), url.QueryEscape(a.options.ClientSecret))
+	}
+
+	res, err := a.options.HttpClient.Do(tokenReq)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed request token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed read token response: %w", err)
+	}
+
+	token := resOAuth2Token{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", 0, fmt.Errorf("token response error with status code %d: %s", res.StatusCode, string(body))
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 || token.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response error with status code %d: %s %s", res.StatusCode, token.Error, token.ErrorDescription)
+	}
+
+	if token.ExpiresIn > 0 {
+		return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+	}
+
+	// the token without expiration is used until the engine rejects it
+	return token.AccessToken, time.Hour * 24 * 365, nil
+}
@@ -0,0 +1,118 @@
+package camunda_client_go
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoAuthentication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{EndpointUrl: server.URL})
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+}
+
+func TestBearerTokenAuthentication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer static-token", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{
+		EndpointUrl:   server.URL,
+		Authenticator: &BearerTokenAuthenticator{Token: "static-token"},
+	})
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+}
+
+func TestOAuth2Authentication(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "worker", user)
+		assert.Equal(t, "secret", password)
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "engine", r.PostForm.Get("scope"))
+
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// the engine revokes the first token
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{
+		EndpointUrl: server.URL,
+		Authenticator: NewOAuth2Authenticator(OAuth2Options{
+			TokenUrl:     tokenServer.URL,
+			ClientId:     "worker",
+			ClientSecret: "secret",
+			Scopes:       []string{"engine"},
+		}),
+	})
+
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&issued))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestOAuth2ShortLivedTokenConcurrently(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		// the lifetime is shorter than the default refresh margin
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":20}`, n)
+	}))
+	defer tokenServer.Close()
+
+	authenticator := NewOAuth2Authenticator(OAuth2Options{
+		TokenUrl:     tokenServer.URL,
+		ClientId:     "worker",
+		ClientSecret: "secret",
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/engine-rest/external-task", nil)
+			assert.NoError(t, authenticator.Authenticate(req))
+			assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+		}()
+	}
+	wg.Wait()
+
+	req := httptest.NewRequest(http.MethodGet, "/engine-rest/external-task", nil)
+	assert.NoError(t, authenticator.Authenticate(req))
+	assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issued))
+}
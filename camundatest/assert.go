@@ -0,0 +1,190 @@
+package camundatest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// AssertExternalTaskCompleted asserts that the external task is completed
+func (e *Engine) AssertExternalTaskCompleted(tb testing.TB, id string) bool {
+	tb.Helper()
+
+	return e.assertExternalTaskState(tb, id, ExternalTaskCompleted)
+}
+
+// AssertExternalTaskBPMNError asserts that a BPMN error with the code is reported for the external task
+func (e *Engine) AssertExternalTaskBPMNError(tb testing.TB, id, errorCode string) bool {
+	tb.Helper()
+
+	if !e.assertExternalTaskState(tb, id, ExternalTaskBPMNError) {
+		return false
+	}
+
+	task, _ := e.ExternalTask(id)
+	if task.ErrorCode != errorCode {
+		tb.Errorf("external task %s has BPMN error code %q, expected %q", id, task.ErrorCode, errorCode)
+		return false
+	}
+
+	return true
+}
+
+// AssertExternalTaskEscalated asserts that an escalation with the code is reported for the external task
+func (e *Engine) AssertExternalTaskEscalated(tb testing.TB, id, escalationCode string) bool {
+	tb.Helper()
+
+	task, ok := e.ExternalTask(id)
+	if !ok {
+		tb.Errorf("external task %s does not exist", id)
+		return false
+	}
+
+	for _, code := range task.EscalationCodes {
+		if code == escalationCode {
+			return true
+		}
+	}
+
+	tb.Errorf("external task %s has escalations %v, expected %q", id, task.EscalationCodes, escalationCode)
+	return false
+}
+
+// AssertExternalTaskFailed asserts that a failure is reported for the external task
+func (e *Engine) AssertExternalTaskFailed(tb testing.TB, id string) bool {
+	tb.Helper()
+
+	task, ok := e.ExternalTask(id)
+	if !ok {
+		tb.Errorf("external task %s does not exist", id)
+		return false
+	}
+
+	if task.Failures == 0 {
+		tb.Errorf("external task %s has no failures, state is %s", id, task.State)
+		return false
+	}
+
+	return true
+}
+
+// AssertIncident asserts that the external task failed without retries left
+func (e *Engine) AssertIncident(tb testing.TB, id string) bool {
+	tb.Helper()
+
+	task, ok := e.ExternalTask(id)
+	if !ok {
+		tb.Errorf("external task %s does not exist", id)
+		return false
+	}
+
+	if !task.HasIncident() {
+		tb.Errorf("external task %s has no incident, state is %s", id, task.State)
+		return false
+	}
+
+	return true
+}
+
+func (e *Engine) assertExternalTaskState(tb testing.TB, id string, state ExternalTaskState) bool {
+	tb.Helper()
+
+	task, ok := e.ExternalTask(id)
+	if !ok {
+		tb.Errorf("external task %s does not exist", id)
+		return false
+	}
+
+	if task.State != state {
+		tb.Errorf("external task %s is %s, expected %s (error message: %q)", id, task.State, state, task.ErrorMessage)
+		return false
+	}
+
+	return true
+}
+
+// AssertProcessInstanceEnded asserts that the process instance is completed
+func (e *Engine) AssertProcessInstanceEnded(tb testing.TB, id string) bool {
+	tb.Helper()
+
+	instance, ok := e.ProcessInstance(id)
+	if !ok {
+		tb.Errorf("process instance %s does not exist", id)
+		return false
+	}
+
+	if instance.State != ProcessInstanceCompleted {
+		tb.Errorf("process instance %s is %s, waits at %v", id, instance.State, instance.ActivityIds)
+		return false
+	}
+
+	return true
+}
+
+// AssertProcessInstanceWaitingAt asserts that the process instance waits at the activity
+func (e *Engine) AssertProcessInstanceWaitingAt(tb testing.TB, id, activityId string) bool {
+	tb.Helper()
+
+	instance, ok := e.ProcessInstance(id)
+	if !ok {
+		tb.Errorf("process instance %s does not exist", id)
+		return false
+	}
+
+	for _, waiting := range instance.ActivityIds {
+		if waiting == activityId {
+			return true
+		}
+	}
+
+	tb.Errorf("process instance %s waits at %v, expected %s", id, instance.ActivityIds, activityId)
+	return false
+}
+
+// AssertVariable asserts the value of the process instance variable, numbers are compared as float64
+func (e *Engine) AssertVariable(tb testing.TB, processInstanceId, name string, value interface{}) bool {
+	tb.Helper()
+
+	instance, ok := e.ProcessInstance(processInstanceId)
+	if !ok {
+		tb.Errorf("process instance %s does not exist", processInstanceId)
+		return false
+	}
+
+	variable, ok := instance.Variables[name]
+	if !ok {
+		tb.Errorf("process instance %s has no variable %s", processInstanceId, name)
+		return false
+	}
+
+	if !reflect.DeepEqual(normalizeValue(variable.Value), normalizeValue(value)) {
+		tb.Errorf("variable %s of process instance %s is %#v, expected %#v", name, processInstanceId, variable.Value, value)
+		return false
+	}
+
+	return true
+}
+
+// AssertMessageCorrelated asserts that the message with the name was correlated to a process instance
+func (e *Engine) AssertMessageCorrelated(tb testing.TB, name string) bool {
+	tb.Helper()
+
+	sent := false
+	for _, message := range e.Messages() {
+		if message.Name != name {
+			continue
+		}
+
+		sent = true
+		if message.ProcessInstanceId != "" {
+			return true
+		}
+	}
+
+	if sent {
+		tb.Errorf("message %s was not correlated", name)
+	} else {
+		tb.Errorf("message %s was not sent", name)
+	}
+
+	return false
+}
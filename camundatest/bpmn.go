@@ -0,0 +1,380 @@
+package camundatest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// maxWalkSteps a limit of elements passed by a token without a wait state, it stops loops in a model
+const maxWalkSteps = 1000
+
+type bpmnDefinitions struct {
+	Processes   []bpmnProcess    `xml:"process"`
+	Messages    []bpmnMessage    `xml:"message"`
+	Errors      []bpmnError      `xml:"error"`
+	Escalations []bpmnEscalation `xml:"escalation"`
+}
+
+type bpmnMessage struct {
+	Id   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type bpmnError struct {
+	Id        string `xml:"id,attr"`
+	Name      string `xml:"name,attr"`
+	ErrorCode string `xml:"errorCode,attr"`
+}
+
+type bpmnEscalation struct {
+	Id             string `xml:"id,attr"`
+	Name           string `xml:"name,attr"`
+	EscalationCode string `xml:"escalationCode,attr"`
+}
+
+type bpmnProcess struct {
+	Id           string        `xml:"id,attr"`
+	Name         string        `xml:"name,attr"`
+	IsExecutable string        `xml:"isExecutable,attr"`
+	Elements     []bpmnElement `xml:",any"`
+}
+
+type bpmnEventDefinition struct {
+	MessageRef    string `xml:"messageRef,attr"`
+	ErrorRef      string `xml:"errorRef,attr"`
+	EscalationRef string `xml:"escalationRef,attr"`
+}
+
+// bpmnElement a flow element or a sequence flow of a process, attributes of the camunda namespace are matched
+// by the local name
+type bpmnElement struct {
+	XMLName        xml.Name
+	Id             string `xml:"id,attr"`
+	Name           string `xml:"name,attr"`
+	Type           string `xml:"type,attr"`
+	Topic          string `xml:"topic,attr"`
+	TaskPriority   string `xml:"taskPriority,attr"`
+	Assignee       string `xml:"assignee,attr"`
+	Default        string `xml:"default,attr"`
+	SourceRef      string `xml:"sourceRef,attr"`
+	TargetRef      string `xml:"targetRef,attr"`
+	AttachedToRef  string `xml:"attachedToRef,attr"`
+	MessageRef     string `xml:"messageRef,attr"`
+	CancelActivity string `xml:"cancelActivity,attr"`
+
+	ConditionExpression       string               `xml:"conditionExpression"`
+	MessageEventDefinition    *bpmnEventDefinition `xml:"messageEventDefinition"`
+	ErrorEventDefinition      *bpmnEventDefinition `xml:"errorEventDefinition"`
+	EscalationEventDefinition *bpmnEventDefinition `xml:"escalationEventDefinition"`
+}
+
+func (e *bpmnElement) kind() string {
+	return e.XMLName.Local
+}
+
+// interrupting returns true if the boundary event cancels the activity it is attached to
+func (e *bpmnElement) interrupting() bool {
+	return e.CancelActivity != "false"
+}
+
+// isExternalTask returns true if the element creates an external task
+func (e *bpmnElement) isExternalTask() bool {
+	switch e.kind() {
+	case "serviceTask", "sendTask", "businessRuleTask":
+		return e.Type == "external"
+	}
+
+	return false
+}
+
+// isWaitState returns true if a token stops at the element until it is triggered from outside
+func (e *bpmnElement) isWaitState() bool {
+	switch e.kind() {
+	case "userTask", "receiveTask", "intermediateCatchEvent":
+		return true
+	}
+
+	return e.isExternalTask()
+}
+
+// passesThrough returns true if a token leaves the element immediately
+func (e *bpmnElement) passesThrough() bool {
+	switch e.kind() {
+	case "startEvent", "intermediateThrowEvent", "task", "manualTask", "scriptTask",
+		"serviceTask", "sendTask", "businessRuleTask", "exclusiveGateway", "parallelGateway":
+		return true
+	}
+
+	return false
+}
+
+// process an executable model of a BPMN process.
+// Supported are events without definitions, message start and catch events, service tasks of the external type,
+// user and receive tasks, error and escalation boundary events, exclusive and parallel gateways.
+// Other tasks complete immediately
+type process struct {
+	id   string
+	name string
+
+	elements   map[string]*bpmnElement
+	outgoing   map[string][]*bpmnElement
+	incoming   map[string]int
+	boundaries map[string][]*bpmnElement
+	// names of messages by id
+	messages map[string]string
+	// codes of errors by id
+	errors map[string]string
+	// codes of escalations by id
+	escalations map[string]string
+}
+
+// parseProcesses returns executable processes of the BPMN 2.0 XML
+func parseProcesses(data []byte) ([]*process, error) {
+	definitions := bpmnDefinitions{}
+	if err := xml.Unmarshal(data, &definitions); err != nil {
+		return nil, fmt.Errorf("failed parse BPMN: %w", err)
+	}
+
+	messages := map[string]string{}
+	for _, m := range definitions.Messages {
+		messages[m.Id] = m.Name
+	}
+
+	errors := map[string]string{}
+	for _, e := range definitions.Errors {
+		errors[e.Id] = e.ErrorCode
+	}
+
+	escalations := map[string]string{}
+	for _, e := range definitions.Escalations {
+		escalations[e.Id] = e.EscalationCode
+	}
+
+	var processes []*process
+	for i := range definitions.Processes {
+		p := &definitions.Processes[i]
+		if p.IsExecutable == "false" {
+			continue
+		}
+
+		parsed := &process{
+			id:          p.Id,
+			name:        p.Name,
+			elements:    map[string]*bpmnElement{},
+			outgoing:    map[string][]*bpmnElement{},
+			incoming:    map[string]int{},
+			boundaries:  map[string][]*bpmnElement{},
+			messages:    messages,
+			errors:      errors,
+			escalations: escalations,
+		}
+
+		for j := range p.Elements {
+			e := &p.Elements[j]
+			switch e.kind() {
+			case "sequenceFlow":
+				parsed.outgoing[e.SourceRef] = append(parsed.outgoing[e.SourceRef], e)
+				parsed.incoming[e.TargetRef]++
+			case "boundaryEvent":
+				parsed.boundaries[e.AttachedToRef] = append(parsed.boundaries[e.AttachedToRef], e)
+				parsed.elements[e.Id] = e
+			default:
+				parsed.elements[e.Id] = e
+			}
+		}
+
+		for _, flows := range parsed.outgoing {
+			for _, flow := range flows {
+				if _, ok := parsed.elements[flow.TargetRef]; !ok {
+					return nil, fmt.Errorf("process %s: sequence flow %s targets unknown element %s", p.Id, flow.Id, flow.TargetRef)
+				}
+			}
+		}
+
+		processes = append(processes, parsed)
+	}
+
+	return processes, nil
+}
+
+// noneStartEvent returns the start event without an event definition
+func (p *process) noneStartEvent() *bpmnElement {
+	for _, e := range p.elements {
+		if e.kind() == "startEvent" && e.MessageEventDefinition == nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// messageStartEvent returns the start event of the message
+func (p *process) messageStartEvent(messageName string) *bpmnElement {
+	for _, e := range p.elements {
+		if e.kind() == "startEvent" && p.messageName(e) == messageName && messageName != "" {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// messageName returns the name of the message which the element waits for
+func (p *process) messageName(e *bpmnElement) string {
+	ref := e.MessageRef
+	if e.MessageEventDefinition != nil {
+		ref = e.MessageEventDefinition.MessageRef
+	}
+
+	if ref == "" {
+		return ""
+	}
+
+	return p.messages[ref]
+}
+
+// errorBoundary returns the boundary event of the activity which catches the error code
+func (p *process) errorBoundary(activityId, errorCode string) *bpmnElement {
+	var catchAll *bpmnElement
+	for _, e := range p.boundaries[activityId] {
+		if e.ErrorEventDefinition == nil {
+			continue
+		}
+
+		if e.ErrorEventDefinition.ErrorRef == "" {
+			catchAll = e
+		} else if p.errors[e.ErrorEventDefinition.ErrorRef] == errorCode {
+			return e
+		}
+	}
+
+	return catchAll
+}
+
+// escalationBoundary returns the boundary event of the activity which catches the escalation code
+func (p *process) escalationBoundary(activityId, escalationCode string) *bpmnElement {
+	var catchAll *bpmnElement
+	for _, e := range p.boundaries[activityId] {
+		if e.EscalationEventDefinition == nil {
+			continue
+		}
+
+		if e.EscalationEventDefinition.EscalationRef == "" {
+			catchAll = e
+		} else if p.escalations[e.EscalationEventDefinition.EscalationRef] == escalationCode {
+			return e
+		}
+	}
+
+	return catchAll
+}
+
+// walk moves a token out of the element until tokens reach wait states or end events.
+// It returns the wait states, tokens arrived at joining parallel gateways are counted in joins
+func (p *process) walk(from *bpmnElement, variables map[string]camundaclientgo.Variable, joins map[string]int) ([]*bpmnElement, error) {
+	queue, err := p.leave(from, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	var waits []*bpmnElement
+	for steps := 0; len(queue) > 0; steps++ {
+		if steps > maxWalkSteps {
+			return nil, fmt.Errorf("process %s: token passed %d elements without a wait state", p.id, maxWalkSteps)
+		}
+
+		flow := queue[0]
+		queue = queue[1:]
+
+		target := p.elements[flow.TargetRef]
+		switch {
+		case target.kind() == "endEvent":
+			continue
+		case target.kind() == "parallelGateway":
+			joins[target.Id]++
+			if joins[target.Id] < p.incoming[target.Id] {
+				continue
+			}
+			joins[target.Id] -= p.incoming[target.Id]
+			if joins[target.Id] == 0 {
+				delete(joins, target.Id)
+			}
+		case target.isWaitState():
+			waits = append(waits, target)
+			continue
+		case !target.passesThrough():
+			return nil, fmt.Errorf("process %s: element %s of type %s is not supported", p.id, target.Id, target.kind())
+		}
+
+		flows, err := p.leave(target, variables)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, flows...)
+	}
+
+	return waits, nil
+}
+
+// leave returns the outgoing sequence flows taken by a token leaving the element
+func (p *process) leave(e *bpmnElement, variables map[string]camundaclientgo.Variable) ([]*bpmnElement, error) {
+	flows := p.outgoing[e.Id]
+	if e.kind() == "parallelGateway" {
+		return flows, nil
+	}
+
+	var taken []*bpmnElement
+	var defaultFlow *bpmnElement
+	for _, flow := range flows {
+		if flow.Id == e.Default {
+			defaultFlow = flow
+			continue
+		}
+
+		ok, err := evaluateCondition(flow.ConditionExpression, variables)
+		if err != nil {
+			return nil, fmt.Errorf("process %s: sequence flow %s: %w", p.id, flow.Id, err)
+		}
+		if !ok {
+			continue
+		}
+
+		taken = append(taken, flow)
+		if e.kind() == "exclusiveGateway" {
+			break
+		}
+	}
+
+	if len(taken) == 0 && defaultFlow != nil {
+		taken = append(taken, defaultFlow)
+	}
+
+	if len(taken) == 0 && e.kind() == "exclusiveGateway" {
+		return nil, fmt.Errorf("process %s: no outgoing sequence flow for the element with id '%s' could be selected for continuing the process", p.id, e.Id)
+	}
+
+	return taken, nil
+}
+
+// evaluateCondition evaluates the condition expression of a sequence flow, an empty condition is true
+func evaluateCondition(condition string, variables map[string]camundaclientgo.Variable) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return true, nil
+	}
+
+	value, err := evaluateExpression(condition, variables)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition expression %s returns non-Boolean: %v", condition, value)
+	}
+
+	return result, nil
+}
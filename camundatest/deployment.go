@@ -0,0 +1,201 @@
+package camundatest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// maxDeploymentSize a limit of a multipart deployment request kept in memory
+const maxDeploymentSize = 32 << 20
+
+// deploymentCreateResponse a response of `/deployment/create` with the field names of the engine
+type deploymentCreateResponse struct {
+	Id                         string                                           `json:"id"`
+	Name                       string                                           `json:"name"`
+	Source                     string                                           `json:"source"`
+	TenantId                   string                                           `json:"tenantId"`
+	DeploymentTime             string                                           `json:"deploymentTime"`
+	Links                      []camundaclientgo.ResLink                        `json:"links"`
+	DeployedProcessDefinitions map[string]*camundaclientgo.ResProcessDefinition `json:"deployedProcessDefinitions"`
+}
+
+func createDeployment(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	if err := r.ParseMultipartForm(maxDeploymentSize); err != nil {
+		return nil, badRequest("failed parse deployment: %s", err)
+	}
+
+	var resources []Resource
+	for name, values := range r.MultipartForm.Value {
+		switch name {
+		case "deployment-name", "deployment-source", "tenant-id", "enable-duplicate-filtering", "deploy-changed-only":
+			continue
+		}
+		resources = append(resources, Resource{Name: name, Data: []byte(values[0])})
+	}
+
+	for name, files := range r.MultipartForm.File {
+		file, err := files[0].Open()
+		if err != nil {
+			return nil, badRequest("failed read resource %s: %s", name, err)
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, badRequest("failed read resource %s: %s", name, err)
+		}
+		resources = append(resources, Resource{Name: name, Data: data})
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+
+	duplicateFiltering := r.FormValue("enable-duplicate-filtering") == "true" || r.FormValue("deploy-changed-only") == "true"
+	deployment, definitions, err := e.deploy(r.FormValue("deployment-name"), r.FormValue("deployment-source"), r.FormValue("tenant-id"), resources, duplicateFiltering)
+	if err != nil {
+		return nil, badRequest("%s", err)
+	}
+
+	res := &deploymentCreateResponse{
+		Id:             deployment.Id,
+		Name:           deployment.Name,
+		Source:         deployment.Source,
+		TenantId:       deployment.TenantId,
+		DeploymentTime: formatTime(deployment.DeploymentTime),
+		Links: []camundaclientgo.ResLink{
+			{Method: http.MethodGet, Href: e.URL() + "/deployment/" + deployment.Id, Rel: "self"},
+		},
+	}
+	if len(definitions) > 0 {
+		res.DeployedProcessDefinitions = map[string]*camundaclientgo.ResProcessDefinition{}
+		for _, definition := range definitions {
+			res.DeployedProcessDefinitions[definition.Id] = definition.response()
+		}
+	}
+
+	return res, nil
+}
+
+func (e *Engine) findDeployments(r *http.Request) []*Deployment {
+	query := r.URL.Query()
+
+	var result []*Deployment
+	for _, deployment := range e.deployments {
+		if (query.Get("id") != "" && deployment.Id != query.Get("id")) ||
+			(query.Get("name") != "" && deployment.Name != query.Get("name")) ||
+			(query.Get("source") != "" && deployment.Source != query.Get("source")) {
+			continue
+		}
+		result = append(result, deployment)
+	}
+
+	return result
+}
+
+func getDeployments(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	deployments := e.findDeployments(r)
+	first, last := page(r, len(deployments))
+
+	res := []*camundaclientgo.ResDeployment{}
+	for _, deployment := range deployments[first:last] {
+		res = append(res, deployment.response())
+	}
+
+	return res, nil
+}
+
+func getDeploymentsCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findDeployments(r))}, nil
+}
+
+func (e *Engine) getDeployment(id string) (*Deployment, error) {
+	for _, deployment := range e.deployments {
+		if deployment.Id == id {
+			return deployment, nil
+		}
+	}
+
+	return nil, notFound("Deployment with id '%s' does not exist", id)
+}
+
+func getDeployment(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	deployment, err := e.getDeployment(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment.response(), nil
+}
+
+func deleteDeployment(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	deployment, err := e.getDeployment(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.deleteDeployment(deployment, r.URL.Query().Get("cascade") == "true"); err != nil {
+		return nil, engineFailure(err)
+	}
+
+	return nil, nil
+}
+
+func getDeploymentResources(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	deployment, err := e.getDeployment(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*camundaclientgo.ResDeploymentResource{}
+	for _, resource := range deployment.Resources {
+		res = append(res, &camundaclientgo.ResDeploymentResource{Id: resource.Id, Name: resource.Name, DeploymentId: deployment.Id})
+	}
+
+	return res, nil
+}
+
+func (e *Engine) getDeploymentResource(deploymentId, id string) (*Deployment, *Resource, error) {
+	deployment, err := e.getDeployment(deploymentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range deployment.Resources {
+		if deployment.Resources[i].Id == id {
+			return deployment, &deployment.Resources[i], nil
+		}
+	}
+
+	return nil, nil, notFound("Deployment resource with resource id '%s' for deployment id '%s' does not exist", id, deploymentId)
+}
+
+func getDeploymentResource(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	deployment, resource, err := e.getDeploymentResource(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &camundaclientgo.ResDeploymentResource{Id: resource.Id, Name: resource.Name, DeploymentId: deployment.Id}, nil
+}
+
+func getDeploymentResourceData(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	_, resource, err := e.getDeploymentResource(params[0], params[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &rawResponse{contentType: "application/octet-stream", data: resource.Data}, nil
+}
+
+func (d *Deployment) response() *camundaclientgo.ResDeployment {
+	return &camundaclientgo.ResDeployment{
+		Id:             d.Id,
+		Name:           d.Name,
+		Source:         d.Source,
+		TenantId:       d.TenantId,
+		DeploymentTime: camundaclientgo.Time{Time: d.DeploymentTime},
+	}
+}
@@ -0,0 +1,35 @@
+// Package camundatest provides an in-memory fake of the Camunda REST API for unit tests without a running engine.
+//
+// The Engine serves the endpoints used by the client over httptest: deployments, process definitions, starting
+// and deleting process instances, process variables, external tasks (fetch and lock with long polling, complete,
+// failure, BPMN error, escalation, extend lock, unlock, retries), user tasks, messages and basic history queries.
+// Endpoints which are not implemented respond with 501 Not Implemented.
+//
+// Deployed BPMN processes are executed with a simplified model: none and message start events, end events,
+// external service tasks, user tasks, receive tasks and intermediate catch events are wait states,
+// exclusive and parallel gateways route tokens, error and escalation boundary events catch BPMN errors and
+// escalations of external tasks and other tasks complete immediately.
+// Conditions support variables, literals, comparisons, `!`, `&&` and `||`.
+// Sub processes, call activities, inclusive and event based gateways are not supported.
+//
+//	engine := camundatest.NewEngine()
+//	defer engine.Close()
+//
+//	task := engine.AddExternalTask("send-invoice", map[string]camundaclientgo.Variable{
+//		"amount": {Value: 42, Type: "Integer"},
+//	})
+//	// run the processor with engine.Client()
+//	engine.WaitFor(t, time.Second, func() bool {
+//		task, _ := engine.ExternalTask(task.Id)
+//		return task.State != camundatest.ExternalTaskActive || task.Failures > 0
+//	})
+//	engine.AssertExternalTaskCompleted(t, task.Id)
+//
+// Recorder and Replayer capture requests and responses of a real engine into fixture files once and serve them
+// back in tests without network. Requests are matched by method, path, query and normalized body, so a changed
+// request payload fails the test:
+//
+//	client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: "http://localhost:8080/engine-rest"})
+//	// CAMUNDA_RECORD=1 go test ./... records the fixture
+//	camundatest.UseFixture(t, client, "testdata/start-order.json")
+package camundatest
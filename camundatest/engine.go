@@ -0,0 +1,1023 @@
+package camundatest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// EngineVersion a version of the engine returned by `/version`
+const EngineVersion = "7.17.0"
+
+// waitInterval an interval to recheck time based conditions, e.g. lock expiration, while waiting for changes
+const waitInterval = 20 * time.Millisecond
+
+// ProcessInstanceState a state of a process instance, the values are states of the history API
+type ProcessInstanceState string
+
+const (
+	ProcessInstanceActive     ProcessInstanceState = "ACTIVE"
+	ProcessInstanceCompleted  ProcessInstanceState = "COMPLETED"
+	ProcessInstanceTerminated ProcessInstanceState = "EXTERNALLY_TERMINATED"
+)
+
+// ExternalTaskState a state of an external task
+type ExternalTaskState string
+
+const (
+	// ExternalTaskActive the task can be fetched, or is locked, or has an incident
+	ExternalTaskActive ExternalTaskState = "active"
+	// ExternalTaskCompleted the task is completed by a worker
+	ExternalTaskCompleted ExternalTaskState = "completed"
+	// ExternalTaskBPMNError a worker reported a BPMN error
+	ExternalTaskBPMNError ExternalTaskState = "bpmnError"
+	// ExternalTaskEscalated a worker reported an escalation caught by an interrupting boundary event
+	ExternalTaskEscalated ExternalTaskState = "escalated"
+	// ExternalTaskDeleted the process instance of the task is terminated
+	ExternalTaskDeleted ExternalTaskState = "deleted"
+)
+
+// UserTaskState a state of a user task
+type UserTaskState string
+
+const (
+	UserTaskActive    UserTaskState = "active"
+	UserTaskCompleted UserTaskState = "completed"
+	UserTaskDeleted   UserTaskState = "deleted"
+)
+
+// Resource a resource of a deployment
+type Resource struct {
+	Id   string
+	Name string
+	Data []byte
+}
+
+// Deployment a deployment of resources
+type Deployment struct {
+	Id             string
+	Name           string
+	Source         string
+	TenantId       string
+	DeploymentTime time.Time
+	Resources      []Resource
+}
+
+// ProcessDefinition a deployed process
+type ProcessDefinition struct {
+	Id           string
+	Key          string
+	Name         string
+	Version      int
+	DeploymentId string
+	// the name of the deployment resource with the process
+	Resource string
+
+	process *process
+}
+
+// ProcessInstance a process instance
+type ProcessInstance struct {
+	Id            string
+	DefinitionId  string
+	DefinitionKey string
+	BusinessKey   string
+	State         ProcessInstanceState
+	Variables     map[string]camundaclientgo.Variable
+	// ids of the activities where the instance waits, e.g. external tasks, user tasks and message events
+	ActivityIds     []string
+	StartActivityId string
+	StartTime       time.Time
+	EndTime         time.Time
+
+	definition *ProcessDefinition
+	// tokens arrived at joining parallel gateways
+	joins map[string]int
+}
+
+// ExternalTask an external task created by a process instance or by Engine.AddExternalTask
+type ExternalTask struct {
+	Id                   string
+	TopicName            string
+	ActivityId           string
+	ProcessInstanceId    string
+	ProcessDefinitionId  string
+	ProcessDefinitionKey string
+	BusinessKey          string
+	Priority             int
+	State                ExternalTaskState
+	// the worker which locked the task last time
+	WorkerId           string
+	LockExpirationTime time.Time
+	Retries            *int
+	// the task is not fetched before this time after a failure with a retry timeout
+	RetryTime    time.Time
+	ErrorMessage string
+	ErrorDetails string
+	// count of failures reported by workers
+	Failures int
+	// the error code of a BPMN error
+	ErrorCode string
+	// codes of escalations reported by workers
+	EscalationCodes []string
+	// variables of the task, they are fetched together with the variables of the process instance
+	LocalVariables map[string]camundaclientgo.Variable
+	// variables sent by a worker with the completion or the BPMN error
+	ResultVariables      map[string]camundaclientgo.Variable
+	ResultLocalVariables map[string]camundaclientgo.Variable
+}
+
+// Locked returns true if the task is locked by a worker at the time
+func (t ExternalTask) Locked(at time.Time) bool {
+	return t.WorkerId != "" && at.Before(t.LockExpirationTime)
+}
+
+// HasIncident returns true if the task failed without retries left
+func (t ExternalTask) HasIncident() bool {
+	return t.State == ExternalTaskActive && t.Retries != nil && *t.Retries <= 0
+}
+
+// UserTask a user task
+type UserTask struct {
+	Id                   string
+	Name                 string
+	TaskDefinitionKey    string
+	ProcessInstanceId    string
+	ProcessDefinitionId  string
+	ProcessDefinitionKey string
+	BusinessKey          string
+	Assignee             string
+	State                UserTaskState
+	Created              time.Time
+	EndTime              time.Time
+	// variables sent with the completion
+	ResultVariables map[string]camundaclientgo.Variable
+}
+
+// Message a message sent to the engine
+type Message struct {
+	Name        string
+	BusinessKey string
+	Variables   map[string]camundaclientgo.Variable
+	// the process instance which received the message or was started by it, empty if the message was not correlated
+	ProcessInstanceId string
+}
+
+// Engine an in-memory fake of the Camunda REST API for unit tests of handlers and services without a running engine.
+// It executes deployed BPMN processes with a simplified model, see the package documentation
+type Engine struct {
+	server *httptest.Server
+
+	mu sync.Mutex
+	// closed and replaced on every change of the state
+	changed  chan struct{}
+	sequence int
+
+	deployments   []*Deployment
+	definitions   []*ProcessDefinition
+	instances     []*ProcessInstance
+	externalTasks []*ExternalTask
+	userTasks     []*UserTask
+	messages      []*Message
+}
+
+// NewEngine starts a new fake engine on a local port, it must be closed by Close
+func NewEngine() *Engine {
+	e := &Engine{changed: make(chan struct{})}
+	e.server = httptest.NewServer(e)
+
+	return e
+}
+
+// Close stops the server of the engine
+func (e *Engine) Close() {
+	e.server.Close()
+}
+
+// URL returns the endpoint url of the engine for ClientOptions.EndpointUrl
+func (e *Engine) URL() string {
+	return e.server.URL + "/engine-rest"
+}
+
+// Client returns a new client of the engine
+func (e *Engine) Client() *camundaclientgo.Client {
+	return camundaclientgo.NewClient(camundaclientgo.ClientOptions{
+		EndpointUrl: e.URL(),
+		Timeout:     time.Second * 10,
+	})
+}
+
+// Deploy deploys the BPMN resource and returns deployed process definitions
+func (e *Engine) Deploy(resourceName string, data []byte) ([]ProcessDefinition, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, definitions, err := e.deploy(resourceName, "", "", []Resource{{Name: resourceName, Data: data}}, false)
+	if err != nil {
+		return nil, err
+	}
+	e.notify()
+
+	result := make([]ProcessDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		result = append(result, *definition)
+	}
+
+	return result, nil
+}
+
+// StartProcessInstance starts the latest version of the process definition with the key
+func (e *Engine) StartProcessInstance(key, businessKey string, variables map[string]camundaclientgo.Variable) (ProcessInstance, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	definition := e.latestDefinition(key)
+	if definition == nil {
+		return ProcessInstance{}, fmt.Errorf("no matching process definition with key: %s", key)
+	}
+
+	instance, err := e.startInstance(definition, definition.process.noneStartEvent(), businessKey, variables)
+	if err != nil {
+		return ProcessInstance{}, err
+	}
+	e.notify()
+
+	return instance.snapshot(), nil
+}
+
+// AddExternalTask adds an external task without a process instance, e.g. to test a handler by a processor.
+// The task is completed without further effects
+func (e *Engine) AddExternalTask(topic string, variables map[string]camundaclientgo.Variable) ExternalTask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	task := &ExternalTask{
+		Id:             e.nextId(),
+		TopicName:      topic,
+		State:          ExternalTaskActive,
+		LocalVariables: copyVariables(variables),
+	}
+	e.externalTasks = append(e.externalTasks, task)
+	e.notify()
+
+	return task.snapshot()
+}
+
+// Trigger moves a token of the process instance waiting at the activity further, e.g. for timer events
+func (e *Engine) Trigger(processInstanceId, activityId string, variables map[string]camundaclientgo.Variable) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instance := e.activeInstance(processInstanceId)
+	if instance == nil {
+		return fmt.Errorf("process instance %s is not active", processInstanceId)
+	}
+
+	if err := e.leave(instance, activityId, nil, variables); err != nil {
+		return err
+	}
+	e.cancelTasks(instance, activityId)
+	e.notify()
+
+	return nil
+}
+
+// Deployments returns all deployments
+func (e *Engine) Deployments() []Deployment {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]Deployment, 0, len(e.deployments))
+	for _, deployment := range e.deployments {
+		result = append(result, *deployment)
+	}
+
+	return result
+}
+
+// ProcessDefinitions returns all deployed process definitions
+func (e *Engine) ProcessDefinitions() []ProcessDefinition {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]ProcessDefinition, 0, len(e.definitions))
+	for _, definition := range e.definitions {
+		result = append(result, *definition)
+	}
+
+	return result
+}
+
+// ProcessInstances returns all process instances including ended ones
+func (e *Engine) ProcessInstances() []ProcessInstance {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]ProcessInstance, 0, len(e.instances))
+	for _, instance := range e.instances {
+		result = append(result, instance.snapshot())
+	}
+
+	return result
+}
+
+// ProcessInstance returns the process instance by id
+func (e *Engine) ProcessInstance(id string) (ProcessInstance, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	instance := e.instance(id)
+	if instance == nil {
+		return ProcessInstance{}, false
+	}
+
+	return instance.snapshot(), true
+}
+
+// ExternalTasks returns all external tasks including completed ones
+func (e *Engine) ExternalTasks() []ExternalTask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]ExternalTask, 0, len(e.externalTasks))
+	for _, task := range e.externalTasks {
+		result = append(result, task.snapshot())
+	}
+
+	return result
+}
+
+// ExternalTask returns the external task by id
+func (e *Engine) ExternalTask(id string) (ExternalTask, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	task := e.externalTask(id)
+	if task == nil {
+		return ExternalTask{}, false
+	}
+
+	return task.snapshot(), true
+}
+
+// UserTasks returns all user tasks including completed ones
+func (e *Engine) UserTasks() []UserTask {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]UserTask, 0, len(e.userTasks))
+	for _, task := range e.userTasks {
+		result = append(result, task.snapshot())
+	}
+
+	return result
+}
+
+// UserTask returns the user task by id
+func (e *Engine) UserTask(id string) (UserTask, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	task := e.userTask(id)
+	if task == nil {
+		return UserTask{}, false
+	}
+
+	return task.snapshot(), true
+}
+
+// Messages returns all messages sent to the engine
+func (e *Engine) Messages() []Message {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]Message, 0, len(e.messages))
+	for _, message := range e.messages {
+		m := *message
+		m.Variables = copyVariables(message.Variables)
+		result = append(result, m)
+	}
+
+	return result
+}
+
+// WaitFor waits until the condition is true, the condition is checked on every change of the engine.
+// The test fails if the condition is not true within the timeout
+func (e *Engine) WaitFor(tb testing.TB, timeout time.Duration, condition func() bool) bool {
+	tb.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		e.mu.Lock()
+		changed := e.changed
+		e.mu.Unlock()
+
+		if condition() {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-time.After(waitInterval):
+		case <-timer.C:
+			tb.Errorf("condition is not met within %s", timeout)
+			return false
+		}
+	}
+}
+
+// notify wakes up waiting fetches and WaitFor, the lock must be held
+func (e *Engine) notify() {
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+func (e *Engine) nextId() string {
+	e.sequence++
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", e.sequence)
+}
+
+// deploy creates a deployment, resources with processes are parsed and deployed as new versions.
+// If duplicateFiltering is true and the resources equal to the last deployment with the name, nothing is deployed
+func (e *Engine) deploy(name, source, tenantId string, resources []Resource, duplicateFiltering bool) (*Deployment, []*ProcessDefinition, error) {
+	if duplicateFiltering {
+		for i := len(e.deployments) - 1; i >= 0; i-- {
+			if e.deployments[i].Name == name {
+				if sameResources(e.deployments[i].Resources, resources) {
+					return e.deployments[i], nil, nil
+				}
+				break
+			}
+		}
+	}
+
+	deployment := &Deployment{
+		Id:             e.nextId(),
+		Name:           name,
+		Source:         source,
+		TenantId:       tenantId,
+		DeploymentTime: time.Now(),
+	}
+
+	var definitions []*ProcessDefinition
+	versions := map[string]int{}
+	for _, resource := range resources {
+		resource.Id = e.nextId()
+		deployment.Resources = append(deployment.Resources, resource)
+
+		if !isBpmnResource(resource) {
+			continue
+		}
+
+		processes, err := parseProcesses(resource.Data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resource %s: %w", resource.Name, err)
+		}
+
+		for _, p := range processes {
+			version := 1
+			if latest := e.latestDefinition(p.id); latest != nil {
+				version = latest.Version + 1
+			}
+			if versions[p.id] > 0 {
+				return nil, nil, fmt.Errorf("the deployment contains definitions with the same key '%s'", p.id)
+			}
+			versions[p.id] = version
+
+			definitions = append(definitions, &ProcessDefinition{
+				Id:           p.id + ":" + strconv.Itoa(version) + ":" + deployment.Id,
+				Key:          p.id,
+				Name:         p.name,
+				Version:      version,
+				DeploymentId: deployment.Id,
+				Resource:     resource.Name,
+				process:      p,
+			})
+		}
+	}
+
+	e.deployments = append(e.deployments, deployment)
+	e.definitions = append(e.definitions, definitions...)
+
+	return deployment, definitions, nil
+}
+
+func isBpmnResource(resource Resource) bool {
+	return strings.HasSuffix(resource.Name, ".bpmn") || strings.HasSuffix(resource.Name, ".bpmn20.xml")
+}
+
+func sameResources(a, b []Resource) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	data := map[string][]byte{}
+	for _, resource := range a {
+		data[resource.Name] = resource.Data
+	}
+	for _, resource := range b {
+		if d, ok := data[resource.Name]; !ok || !bytes.Equal(d, resource.Data) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deleteDeployment deletes the deployment, running instances are terminated if cascade is true
+func (e *Engine) deleteDeployment(deployment *Deployment, cascade bool) error {
+	var instances []*ProcessInstance
+	for _, instance := range e.instances {
+		if instance.State == ProcessInstanceActive && instance.definition.DeploymentId == deployment.Id {
+			instances = append(instances, instance)
+		}
+	}
+
+	if len(instances) > 0 && !cascade {
+		return fmt.Errorf("deletion of process definition without cascading failed, there are %d process instances", len(instances))
+	}
+
+	for _, instance := range instances {
+		e.endInstance(instance, ProcessInstanceTerminated)
+	}
+
+	definitions := e.definitions[:0]
+	for _, definition := range e.definitions {
+		if definition.DeploymentId != deployment.Id {
+			definitions = append(definitions, definition)
+		}
+	}
+	e.definitions = definitions
+
+	for i, d := range e.deployments {
+		if d == deployment {
+			e.deployments = append(e.deployments[:i], e.deployments[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) latestDefinition(key string) *ProcessDefinition {
+	var latest *ProcessDefinition
+	for _, definition := range e.definitions {
+		if definition.Key == key && (latest == nil || definition.Version > latest.Version) {
+			latest = definition
+		}
+	}
+
+	return latest
+}
+
+func (e *Engine) definition(id string) *ProcessDefinition {
+	for _, definition := range e.definitions {
+		if definition.Id == id {
+			return definition
+		}
+	}
+
+	return nil
+}
+
+// startInstance starts a process instance at the start event, no state is changed if the start fails
+func (e *Engine) startInstance(definition *ProcessDefinition, start *bpmnElement, businessKey string, variables map[string]camundaclientgo.Variable) (*ProcessInstance, error) {
+	if start == nil {
+		return nil, fmt.Errorf("process definition %s has no start event", definition.Id)
+	}
+
+	variables = mergeVariables(nil, variables)
+	joins := map[string]int{}
+	waits, err := definition.process.walk(start, variables, joins)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &ProcessInstance{
+		Id:              e.nextId(),
+		DefinitionId:    definition.Id,
+		DefinitionKey:   definition.Key,
+		BusinessKey:     businessKey,
+		State:           ProcessInstanceActive,
+		Variables:       variables,
+		StartActivityId: start.Id,
+		StartTime:       time.Now(),
+		definition:      definition,
+		joins:           joins,
+	}
+	e.instances = append(e.instances, instance)
+	e.enter(instance, waits)
+
+	return instance, nil
+}
+
+// leave moves the token waiting at the activity out of the element, it is the activity itself or its boundary event.
+// Variables are set before, no state is changed if the token cannot be moved
+func (e *Engine) leave(instance *ProcessInstance, activityId string, from *bpmnElement, variables map[string]camundaclientgo.Variable) error {
+	index := -1
+	for i, id := range instance.ActivityIds {
+		if id == activityId {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("process instance %s does not wait at activity %s", instance.Id, activityId)
+	}
+
+	if from == nil {
+		from = instance.definition.process.elements[activityId]
+	}
+
+	merged := mergeVariables(instance.Variables, variables)
+	joins := map[string]int{}
+	for id, count := range instance.joins {
+		joins[id] = count
+	}
+
+	waits, err := instance.definition.process.walk(from, merged, joins)
+	if err != nil {
+		return err
+	}
+
+	instance.Variables = merged
+	instance.joins = joins
+	instance.ActivityIds = append(instance.ActivityIds[:index], instance.ActivityIds[index+1:]...)
+	e.enter(instance, waits)
+
+	return nil
+}
+
+// enter creates tasks of the wait states, the instance is completed if it has no tokens left
+func (e *Engine) enter(instance *ProcessInstance, waits []*bpmnElement) {
+	for _, wait := range waits {
+		instance.ActivityIds = append(instance.ActivityIds, wait.Id)
+
+		switch {
+		case wait.isExternalTask():
+			priority, _ := strconv.Atoi(wait.TaskPriority)
+			e.externalTasks = append(e.externalTasks, &ExternalTask{
+				Id:                   e.nextId(),
+				TopicName:            wait.Topic,
+				ActivityId:           wait.Id,
+				ProcessInstanceId:    instance.Id,
+				ProcessDefinitionId:  instance.DefinitionId,
+				ProcessDefinitionKey: instance.DefinitionKey,
+				BusinessKey:          instance.BusinessKey,
+				Priority:             priority,
+				State:                ExternalTaskActive,
+			})
+		case wait.kind() == "userTask":
+			e.userTasks = append(e.userTasks, &UserTask{
+				Id:                   e.nextId(),
+				Name:                 wait.Name,
+				TaskDefinitionKey:    wait.Id,
+				ProcessInstanceId:    instance.Id,
+				ProcessDefinitionId:  instance.DefinitionId,
+				ProcessDefinitionKey: instance.DefinitionKey,
+				BusinessKey:          instance.BusinessKey,
+				Assignee:             wait.Assignee,
+				State:                UserTaskActive,
+				Created:              time.Now(),
+			})
+		}
+	}
+
+	if len(instance.ActivityIds) == 0 && len(instance.joins) == 0 {
+		e.endInstance(instance, ProcessInstanceCompleted)
+	}
+}
+
+// endInstance ends the instance, active tasks are deleted
+func (e *Engine) endInstance(instance *ProcessInstance, state ProcessInstanceState) {
+	instance.State = state
+	instance.EndTime = time.Now()
+	for len(instance.ActivityIds) > 0 {
+		e.cancelTasks(instance, instance.ActivityIds[0])
+		instance.ActivityIds = instance.ActivityIds[1:]
+	}
+}
+
+// cancelTasks deletes an active task of the instance at the activity, if the token has left the activity
+func (e *Engine) cancelTasks(instance *ProcessInstance, activityId string) {
+	waiting := 0
+	for _, id := range instance.ActivityIds {
+		if id == activityId {
+			waiting++
+		}
+	}
+
+	active := 0
+	for _, task := range e.externalTasks {
+		if task.ProcessInstanceId == instance.Id && task.ActivityId == activityId && task.State == ExternalTaskActive {
+			active++
+			if active > waiting || instance.State != ProcessInstanceActive {
+				task.State = ExternalTaskDeleted
+			}
+		}
+	}
+
+	active = 0
+	for _, task := range e.userTasks {
+		if task.ProcessInstanceId == instance.Id && task.TaskDefinitionKey == activityId && task.State == UserTaskActive {
+			active++
+			if active > waiting || instance.State != ProcessInstanceActive {
+				task.State = UserTaskDeleted
+				task.EndTime = time.Now()
+			}
+		}
+	}
+}
+
+// completeExternalTask completes the task and moves the process instance further
+func (e *Engine) completeExternalTask(task *ExternalTask, variables, localVariables map[string]camundaclientgo.Variable) error {
+	if instance := e.activeInstance(task.ProcessInstanceId); instance != nil {
+		if err := e.leave(instance, task.ActivityId, nil, variables); err != nil {
+			return err
+		}
+	}
+
+	task.State = ExternalTaskCompleted
+	task.ResultVariables = copyVariables(variables)
+	task.ResultLocalVariables = copyVariables(localVariables)
+
+	return nil
+}
+
+// handleBPMNError moves the process instance to the error boundary event catching the code.
+// Without a boundary event the token is consumed
+func (e *Engine) handleBPMNError(task *ExternalTask, errorCode string, variables map[string]camundaclientgo.Variable) error {
+	if instance := e.activeInstance(task.ProcessInstanceId); instance != nil {
+		boundary := instance.definition.process.errorBoundary(task.ActivityId, errorCode)
+		if boundary != nil {
+			if err := e.leave(instance, task.ActivityId, boundary, variables); err != nil {
+				return err
+			}
+		} else {
+			if err := e.consume(instance, task.ActivityId, variables); err != nil {
+				return err
+			}
+		}
+	}
+
+	task.State = ExternalTaskBPMNError
+	task.ErrorCode = errorCode
+	task.ResultVariables = copyVariables(variables)
+
+	return nil
+}
+
+// handleEscalation moves the process instance to the escalation boundary event catching the code.
+// The task stays active unless the boundary event is interrupting, an escalation without a boundary event
+// only sets the variables
+func (e *Engine) handleEscalation(task *ExternalTask, escalationCode string, variables map[string]camundaclientgo.Variable) error {
+	if instance := e.activeInstance(task.ProcessInstanceId); instance != nil {
+		boundary := instance.definition.process.escalationBoundary(task.ActivityId, escalationCode)
+		switch {
+		case boundary == nil:
+			instance.Variables = mergeVariables(instance.Variables, variables)
+		case boundary.interrupting():
+			if err := e.leave(instance, task.ActivityId, boundary, variables); err != nil {
+				return err
+			}
+			task.State = ExternalTaskEscalated
+			task.ResultVariables = copyVariables(variables)
+		default:
+			if err := e.spawn(instance, boundary, variables); err != nil {
+				return err
+			}
+		}
+	}
+
+	task.EscalationCodes = append(task.EscalationCodes, escalationCode)
+
+	return nil
+}
+
+// spawn moves a new token out of the element, e.g. a non-interrupting boundary event
+func (e *Engine) spawn(instance *ProcessInstance, from *bpmnElement, variables map[string]camundaclientgo.Variable) error {
+	merged := mergeVariables(instance.Variables, variables)
+	joins := map[string]int{}
+	for id, count := range instance.joins {
+		joins[id] = count
+	}
+
+	waits, err := instance.definition.process.walk(from, merged, joins)
+	if err != nil {
+		return err
+	}
+
+	instance.Variables = merged
+	instance.joins = joins
+	e.enter(instance, waits)
+
+	return nil
+}
+
+// consume removes the token at the activity without moving it further
+func (e *Engine) consume(instance *ProcessInstance, activityId string, variables map[string]camundaclientgo.Variable) error {
+	for i, id := range instance.ActivityIds {
+		if id == activityId {
+			for name, variable := range variables {
+				instance.Variables[name] = variable
+			}
+			instance.ActivityIds = append(instance.ActivityIds[:i], instance.ActivityIds[i+1:]...)
+			e.enter(instance, nil)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("process instance %s does not wait at activity %s", instance.Id, activityId)
+}
+
+// completeUserTask completes the task and moves the process instance further
+func (e *Engine) completeUserTask(task *UserTask, variables map[string]camundaclientgo.Variable) error {
+	if instance := e.activeInstance(task.ProcessInstanceId); instance != nil {
+		if err := e.leave(instance, task.TaskDefinitionKey, nil, variables); err != nil {
+			return err
+		}
+	}
+
+	task.State = UserTaskCompleted
+	task.EndTime = time.Now()
+	task.ResultVariables = copyVariables(variables)
+
+	return nil
+}
+
+// correlateMessage delivers the message to a waiting process instance or starts a process by a message start event
+func (e *Engine) correlateMessage(message *Message) error {
+	for _, instance := range e.instances {
+		if instance.State != ProcessInstanceActive || (message.BusinessKey != "" && instance.BusinessKey != message.BusinessKey) {
+			continue
+		}
+
+		p := instance.definition.process
+		for _, activityId := range instance.ActivityIds {
+			if p.messageName(p.elements[activityId]) != message.Name {
+				continue
+			}
+
+			if err := e.leave(instance, activityId, nil, message.Variables); err != nil {
+				return err
+			}
+			message.ProcessInstanceId = instance.Id
+			return nil
+		}
+	}
+
+	keys := map[string]bool{}
+	for i := len(e.definitions) - 1; i >= 0; i-- {
+		definition := e.definitions[i]
+		if keys[definition.Key] {
+			continue
+		}
+		keys[definition.Key] = true
+
+		if definition != e.latestDefinition(definition.Key) {
+			continue
+		}
+
+		if start := definition.process.messageStartEvent(message.Name); start != nil {
+			instance, err := e.startInstance(definition, start, message.BusinessKey, message.Variables)
+			if err != nil {
+				return err
+			}
+			message.ProcessInstanceId = instance.Id
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot correlate message '%s': no process definition or execution matches the parameters", message.Name)
+}
+
+func (e *Engine) instance(id string) *ProcessInstance {
+	for _, instance := range e.instances {
+		if instance.Id == id {
+			return instance
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) activeInstance(id string) *ProcessInstance {
+	if instance := e.instance(id); instance != nil && instance.State == ProcessInstanceActive {
+		return instance
+	}
+
+	return nil
+}
+
+func (e *Engine) externalTask(id string) *ExternalTask {
+	for _, task := range e.externalTasks {
+		if task.Id == id {
+			return task
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) userTask(id string) *UserTask {
+	for _, task := range e.userTasks {
+		if task.Id == id {
+			return task
+		}
+	}
+
+	return nil
+}
+
+// taskVariables returns variables fetched with the task, all variables if names is empty
+func (e *Engine) taskVariables(task *ExternalTask, names []string) map[string]camundaclientgo.Variable {
+	all := map[string]camundaclientgo.Variable{}
+	if instance := e.instance(task.ProcessInstanceId); instance != nil {
+		for name, variable := range instance.Variables {
+			all[name] = variable
+		}
+	}
+	for name, variable := range task.LocalVariables {
+		all[name] = variable
+	}
+
+	if len(names) == 0 {
+		return all
+	}
+
+	selected := map[string]camundaclientgo.Variable{}
+	for _, name := range names {
+		if variable, ok := all[name]; ok {
+			selected[name] = variable
+		}
+	}
+
+	return selected
+}
+
+func (i *ProcessInstance) snapshot() ProcessInstance {
+	s := *i
+	s.Variables = copyVariables(i.Variables)
+	s.ActivityIds = append([]string(nil), i.ActivityIds...)
+	sort.Strings(s.ActivityIds)
+	s.joins = nil
+
+	return s
+}
+
+func (t *ExternalTask) snapshot() ExternalTask {
+	s := *t
+	s.LocalVariables = copyVariables(t.LocalVariables)
+	s.ResultVariables = copyVariables(t.ResultVariables)
+	s.ResultLocalVariables = copyVariables(t.ResultLocalVariables)
+	s.EscalationCodes = append([]string(nil), t.EscalationCodes...)
+	if t.Retries != nil {
+		retries := *t.Retries
+		s.Retries = &retries
+	}
+
+	return s
+}
+
+func (t *UserTask) snapshot() UserTask {
+	s := *t
+	s.ResultVariables = copyVariables(t.ResultVariables)
+
+	return s
+}
+
+func copyVariables(variables map[string]camundaclientgo.Variable) map[string]camundaclientgo.Variable {
+	if variables == nil {
+		return nil
+	}
+
+	result := make(map[string]camundaclientgo.Variable, len(variables))
+	for name, variable := range variables {
+		result[name] = variable
+	}
+
+	return result
+}
+
+// mergeVariables returns a new map with the variables updated by updates
+func mergeVariables(variables, updates map[string]camundaclientgo.Variable) map[string]camundaclientgo.Variable {
+	result := make(map[string]camundaclientgo.Variable, len(variables)+len(updates))
+	for name, variable := range variables {
+		result[name] = variable
+	}
+	for name, variable := range updates {
+		result[name] = variable
+	}
+
+	return result
+}
@@ -0,0 +1,344 @@
+package camundatest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+const orderProcess = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:camunda="http://camunda.org/schema/1.0/bpmn" id="Definitions_1">
+  <bpmn:process id="order" name="Order" isExecutable="true">
+    <bpmn:startEvent id="start" />
+    <bpmn:sequenceFlow id="f1" sourceRef="start" targetRef="fork" />
+    <bpmn:parallelGateway id="fork" />
+    <bpmn:sequenceFlow id="f2" sourceRef="fork" targetRef="charge" />
+    <bpmn:sequenceFlow id="f3" sourceRef="fork" targetRef="reserve" />
+    <bpmn:serviceTask id="charge" camunda:type="external" camunda:topic="charge" />
+    <bpmn:serviceTask id="reserve" camunda:type="external" camunda:topic="reserve" />
+    <bpmn:boundaryEvent id="declined" attachedToRef="charge">
+      <bpmn:errorEventDefinition errorRef="Error_declined" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f4" sourceRef="charge" targetRef="paid" />
+    <bpmn:sequenceFlow id="f5" sourceRef="reserve" targetRef="join" />
+    <bpmn:sequenceFlow id="f6" sourceRef="declined" targetRef="review" />
+    <bpmn:userTask id="review" name="Review payment" camunda:assignee="demo" />
+    <bpmn:sequenceFlow id="f7" sourceRef="review" targetRef="paid" />
+    <bpmn:exclusiveGateway id="paid" />
+    <bpmn:sequenceFlow id="f10" sourceRef="paid" targetRef="join" />
+    <bpmn:parallelGateway id="join" />
+    <bpmn:sequenceFlow id="f8" sourceRef="join" targetRef="shipped" />
+    <bpmn:intermediateCatchEvent id="shipped">
+      <bpmn:messageEventDefinition messageRef="Message_shipped" />
+    </bpmn:intermediateCatchEvent>
+    <bpmn:sequenceFlow id="f9" sourceRef="shipped" targetRef="end" />
+    <bpmn:endEvent id="end" />
+  </bpmn:process>
+  <bpmn:error id="Error_declined" name="Declined" errorCode="payment-declined" />
+  <bpmn:message id="Message_shipped" name="OrderShipped" />
+</bpmn:definitions>`
+
+func TestHelloWorldProcess(t *testing.T) {
+	engine := NewEngine()
+	defer engine.Close()
+	client := engine.Client()
+
+	file, err := os.Open("../examples/deployment/HelloWorld.bpmn")
+	require.NoError(t, err)
+	deployment, err := client.Deployment.Create(camundaclientgo.ReqDeploymentCreate{
+		DeploymentName: "HelloWorldProcessDemo",
+		Resources:      map[string]interface{}{"HelloWorld.bpmn": file},
+	})
+	require.NoError(t, err)
+	require.Len(t, deployment.DeployedProcessDefinitions, 1)
+
+	key := "hello-world-process"
+	for _, isWorld := range []bool{true, false} {
+		started, err := client.ProcessDefinition.StartInstance(
+			camundaclientgo.QueryProcessDefinitionBy{Key: &key},
+			camundaclientgo.ReqStartInstance{Variables: &map[string]camundaclientgo.Variable{
+				"isWorld": {Value: isWorld, Type: "Boolean"},
+			}},
+		)
+		require.NoError(t, err)
+
+		topic, activityId := "PrintHello", "Task_18yse8m"
+		if isWorld {
+			topic, activityId = "PrintWorld", "Task_1s4a9px"
+		}
+		engine.AssertProcessInstanceWaitingAt(t, started.Id, activityId)
+
+		tasks, err := client.ExternalTask.FetchAndLock(camundaclientgo.QueryFetchAndLock{
+			WorkerId: "worker",
+			MaxTasks: 10,
+			Topics:   []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: topic, LockDuration: 1000}},
+		})
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.Equal(t, isWorld, tasks[0].Variables["isWorld"].Value)
+
+		worker := "worker"
+		require.NoError(t, client.ExternalTask.Complete(tasks[0].Id, camundaclientgo.QueryComplete{
+			WorkerId:  &worker,
+			Variables: &map[string]camundaclientgo.Variable{"printed": {Value: topic, Type: "String"}},
+		}))
+
+		engine.AssertExternalTaskCompleted(t, tasks[0].Id)
+		engine.AssertProcessInstanceEnded(t, started.Id)
+		engine.AssertVariable(t, started.Id, "printed", topic)
+
+		history, err := client.History.GetProcessInstance(started.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "COMPLETED", history.State)
+	}
+
+	count, err := client.ExternalTask.GetListCount(map[string]string{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestGatewaysBoundaryEventsAndMessages(t *testing.T) {
+	engine := NewEngine()
+	defer engine.Close()
+	client := engine.Client()
+
+	_, err := engine.Deploy("order.bpmn", []byte(orderProcess))
+	require.NoError(t, err)
+	instance, err := engine.StartProcessInstance("order", "order-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"charge", "reserve"}, instance.ActivityIds)
+
+	worker := "worker"
+	tasks, err := client.ExternalTask.FetchAndLock(camundaclientgo.QueryFetchAndLock{
+		WorkerId: worker,
+		MaxTasks: 10,
+		Topics: []*camundaclientgo.QueryFetchAndLockTopic{
+			{TopicName: "charge", LockDuration: 1000},
+			{TopicName: "reserve", LockDuration: 1000},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+
+	for _, task := range tasks {
+		if task.TopicName == "charge" {
+			code := "payment-declined"
+			require.NoError(t, client.ExternalTask.HandleBPMNError(task.Id, camundaclientgo.QueryHandleBPMNError{
+				WorkerId:  &worker,
+				ErrorCode: &code,
+			}))
+			engine.AssertExternalTaskBPMNError(t, task.Id, code)
+		} else {
+			require.NoError(t, client.ExternalTask.Complete(task.Id, camundaclientgo.QueryComplete{WorkerId: &worker}))
+		}
+	}
+	engine.AssertProcessInstanceWaitingAt(t, instance.Id, "review")
+
+	userTasks, err := client.UserTask.GetList(&camundaclientgo.UserTaskGetListQuery{ProcessInstanceId: instance.Id})
+	require.NoError(t, err)
+	require.Len(t, userTasks, 1)
+	assert.Equal(t, "demo", userTasks[0].Assignee)
+	require.NoError(t, userTasks[0].Complete(camundaclientgo.QueryUserTaskComplete{
+		Variables: map[string]camundaclientgo.Variable{"approved": {Value: true, Type: "Boolean"}},
+	}))
+	engine.AssertProcessInstanceWaitingAt(t, instance.Id, "shipped")
+
+	assert.Error(t, client.Message.SendMessage(&camundaclientgo.ReqMessage{MessageName: "OrderShipped", BusinessKey: "order-2"}))
+	require.NoError(t, client.Message.SendMessage(&camundaclientgo.ReqMessage{MessageName: "OrderShipped", BusinessKey: "order-1"}))
+	engine.AssertMessageCorrelated(t, "OrderShipped")
+	engine.AssertProcessInstanceEnded(t, instance.Id)
+	engine.AssertVariable(t, instance.Id, "approved", true)
+}
+
+func TestExternalTaskFailures(t *testing.T) {
+	engine := NewEngine()
+	defer engine.Close()
+	client := engine.Client()
+
+	task := engine.AddExternalTask("flaky", nil)
+	worker := "worker"
+	query := camundaclientgo.QueryFetchAndLock{
+		WorkerId: worker,
+		MaxTasks: 1,
+		Topics:   []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "flaky", LockDuration: 1000}},
+	}
+
+	tasks, err := client.ExternalTask.FetchAndLock(query)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+
+	other := "other-worker"
+	err = client.ExternalTask.Complete(task.Id, camundaclientgo.QueryComplete{WorkerId: &other})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "locked by worker 'worker'")
+	}
+
+	tasks, err = client.ExternalTask.FetchAndLock(query)
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "the task is locked")
+
+	retries, retryTimeout, message := 1, 50, "connection refused"
+	require.NoError(t, client.ExternalTask.HandleFailure(task.Id, camundaclientgo.QueryHandleFailure{
+		WorkerId:     &worker,
+		ErrorMessage: &message,
+		Retries:      &retries,
+		RetryTimeout: &retryTimeout,
+	}))
+	engine.AssertExternalTaskFailed(t, task.Id)
+
+	timeout := 1000
+	query.AsyncResponseTimeout = &timeout
+	tasks, err = client.ExternalTask.FetchAndLock(query)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1, "the task is fetched after the retry timeout")
+	assert.Equal(t, message, tasks[0].ErrorMessage)
+
+	require.NoError(t, client.ExternalTask.HandleFailure(task.Id, camundaclientgo.QueryHandleFailure{WorkerId: &worker}))
+	engine.AssertIncident(t, task.Id)
+}
+
+func TestLongPolling(t *testing.T) {
+	engine := NewEngine()
+	defer engine.Close()
+	client := engine.Client()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		engine.AddExternalTask("late", nil)
+	}()
+
+	timeout := 5000
+	started := time.Now()
+	tasks, err := client.ExternalTask.FetchAndLock(camundaclientgo.QueryFetchAndLock{
+		WorkerId:             "worker",
+		MaxTasks:             1,
+		AsyncResponseTimeout: &timeout,
+		Topics:               []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "late", LockDuration: 1000}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Less(t, int64(time.Since(started)), int64(time.Second))
+}
+
+const escalationProcess = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:camunda="http://camunda.org/schema/1.0/bpmn" id="Definitions_1">
+  <bpmn:process id="check" isExecutable="true">
+    <bpmn:startEvent id="start" />
+    <bpmn:sequenceFlow id="f1" sourceRef="start" targetRef="verify" />
+    <bpmn:serviceTask id="verify" camunda:type="external" camunda:topic="verify" />
+    <bpmn:sequenceFlow id="f2" sourceRef="verify" targetRef="end" />
+    <bpmn:boundaryEvent id="slow" attachedToRef="verify" cancelActivity="false">
+      <bpmn:escalationEventDefinition escalationRef="Escalation_slow" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f3" sourceRef="slow" targetRef="inform" />
+    <bpmn:userTask id="inform" />
+    <bpmn:sequenceFlow id="f4" sourceRef="inform" targetRef="end" />
+    <bpmn:boundaryEvent id="manual" attachedToRef="verify">
+      <bpmn:escalationEventDefinition escalationRef="Escalation_manual" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f5" sourceRef="manual" targetRef="review" />
+    <bpmn:userTask id="review" />
+    <bpmn:sequenceFlow id="f6" sourceRef="review" targetRef="end" />
+    <bpmn:endEvent id="end" />
+  </bpmn:process>
+  <bpmn:escalation id="Escalation_slow" escalationCode="slow" />
+  <bpmn:escalation id="Escalation_manual" escalationCode="manual" />
+</bpmn:definitions>`
+
+func TestEscalations(t *testing.T) {
+	engine := NewEngine()
+	defer engine.Close()
+	client := engine.Client()
+
+	_, err := engine.Deploy("check.bpmn", []byte(escalationProcess))
+	require.NoError(t, err)
+	instance, err := engine.StartProcessInstance("check", "", nil)
+	require.NoError(t, err)
+
+	worker := "worker"
+	tasks, err := client.ExternalTask.FetchAndLock(camundaclientgo.QueryFetchAndLock{
+		WorkerId: worker,
+		MaxTasks: 1,
+		Topics:   []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "verify", LockDuration: 10000}},
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+
+	unknown, slow, manual := "unknown", "slow", "manual"
+	require.NoError(t, client.ExternalTask.HandleEscalation(tasks[0].Id, camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &worker,
+		EscalationCode: &unknown,
+		Variables:      &map[string]camundaclientgo.Variable{"escalated": {Value: true, Type: "Boolean"}},
+	}))
+	engine.AssertVariable(t, instance.Id, "escalated", true)
+	assert.Equal(t, []string{"verify"}, mustInstance(t, engine, instance.Id).ActivityIds)
+
+	require.NoError(t, client.ExternalTask.HandleEscalation(tasks[0].Id, camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &worker,
+		EscalationCode: &slow,
+	}))
+	engine.AssertExternalTaskEscalated(t, tasks[0].Id, "slow")
+	assert.ElementsMatch(t, []string{"verify", "inform"}, mustInstance(t, engine, instance.Id).ActivityIds)
+
+	require.NoError(t, client.ExternalTask.HandleEscalation(tasks[0].Id, camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &worker,
+		EscalationCode: &manual,
+	}))
+	if task, ok := engine.ExternalTask(tasks[0].Id); assert.True(t, ok) {
+		assert.Equal(t, ExternalTaskEscalated, task.State)
+		assert.Equal(t, []string{"unknown", "slow", "manual"}, task.EscalationCodes)
+	}
+	assert.ElementsMatch(t, []string{"inform", "review"}, mustInstance(t, engine, instance.Id).ActivityIds)
+
+	err = client.ExternalTask.HandleEscalation(tasks[0].Id, camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &worker,
+		EscalationCode: &manual,
+	})
+	assert.Error(t, err)
+}
+
+func mustInstance(t *testing.T, engine *Engine, id string) ProcessInstance {
+	instance, ok := engine.ProcessInstance(id)
+	require.True(t, ok, "process instance %s does not exist", id)
+
+	return instance
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	variables := map[string]camundaclientgo.Variable{
+		"amount":   {Value: float64(150)},
+		"count":    {Value: 3},
+		"approved": {Value: true},
+		"country":  {Value: "DE"},
+	}
+
+	tests := map[string]bool{
+		"":                                           true,
+		"${approved}":                                true,
+		"${!approved}":                               false,
+		"${amount > 100}":                            true,
+		"${amount <= 100 || country == 'DE'}":        true,
+		"${amount gt 100 and not approved}":          false,
+		"${count == 3 && (country != \"FR\")}":       true,
+		"#{approved && (amount >= 150.0)}":           true,
+		"${country == 'DE' && count lt 2}":           false,
+		"${approved == true && country eq 'DE'}":     true,
+		"${(amount < 100 || count > 2) && approved}": true,
+	}
+
+	for condition, expected := range tests {
+		result, err := evaluateCondition(condition, variables)
+		if assert.NoError(t, err, condition) {
+			assert.Equal(t, expected, result, condition)
+		}
+	}
+
+	_, err := evaluateCondition("${amount}", variables)
+	assert.Error(t, err, "non-Boolean result")
+	_, err = evaluateCondition("${unknown == null}", variables)
+	assert.Error(t, err, "unknown variable")
+}
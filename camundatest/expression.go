@@ -0,0 +1,375 @@
+package camundatest
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// expression a compiled subset of JUEL expressions: variables, literals, comparisons, `!`, `&&`, `||` and parentheses
+type expression func(variables map[string]camundaclientgo.Variable) (interface{}, error)
+
+var keywordOperators = map[string]string{
+	"and": "&&",
+	"or":  "||",
+	"not": "!",
+	"eq":  "==",
+	"ne":  "!=",
+	"lt":  "<",
+	"gt":  ">",
+	"le":  "<=",
+	"ge":  ">=",
+}
+
+// evaluateExpression evaluates the expression `${...}` with the variables
+func evaluateExpression(source string, variables map[string]camundaclientgo.Variable) (interface{}, error) {
+	if !(strings.HasPrefix(source, "${") || strings.HasPrefix(source, "#{")) || !strings.HasSuffix(source, "}") {
+		return nil, fmt.Errorf("unsupported expression %s", source)
+	}
+
+	tokens, err := tokenize(source[2 : len(source)-1])
+	if err != nil {
+		return nil, fmt.Errorf("failed parse expression %s: %w", source, err)
+	}
+
+	p := &expressionParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed parse expression %s: %w", source, err)
+	}
+
+	value, err := expr(variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed evaluate expression %s: %w", source, err)
+	}
+
+	return value, nil
+}
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenIdentifier
+	tokenNumber
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			if operator, ok := keywordOperators[word]; ok {
+				tokens = append(tokens, token{kind: tokenOperator, text: operator})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdentifier, text: word})
+			}
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			operator := string(r)
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					operator = two
+				}
+			}
+			if !strings.Contains("==!=<=>=&&||()", operator) {
+				return nil, fmt.Errorf("unexpected %s", operator)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator})
+			i += len(operator)
+		}
+	}
+
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *expressionParser) peekOperator(operators ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+
+	for _, operator := range operators {
+		if p.tokens[p.pos].text == operator {
+			return operator, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *expressionParser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peekOperator("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+}
+
+func (p *expressionParser) parseAnd() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.peekOperator("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+}
+
+func (p *expressionParser) parseUnary() (expression, error) {
+	if _, ok := p.peekOperator("!"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(variables map[string]camundaclientgo.Variable) (interface{}, error) {
+			value, err := operand(variables)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("cannot negate %v", value)
+			}
+			return !b, nil
+		}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (expression, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	operator, ok := p.peekOperator("==", "!=", "<", ">", "<=", ">=")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(variables map[string]camundaclientgo.Variable) (interface{}, error) {
+		a, err := left(variables)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(variables)
+		if err != nil {
+			return nil, err
+		}
+
+		return compare(operator, a, b)
+	}, nil
+}
+
+func (p *expressionParser) parsePrimary() (expression, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case tokenNumber:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return constant(number), nil
+	case tokenString:
+		return constant(t.text), nil
+	case tokenIdentifier:
+		switch t.text {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		case "null":
+			return constant(nil), nil
+		}
+
+		name := t.text
+		return func(variables map[string]camundaclientgo.Variable) (interface{}, error) {
+			variable, ok := variables[name]
+			if !ok {
+				return nil, fmt.Errorf("cannot resolve identifier '%s'", name)
+			}
+			return normalizeValue(variable.Value), nil
+		}, nil
+	}
+
+	if t.text != "(" {
+		return nil, fmt.Errorf("unexpected %s", t.text)
+	}
+
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peekOperator(")"); !ok {
+		return nil, fmt.Errorf("missing )")
+	}
+	p.pos++
+
+	return inner, nil
+}
+
+func constant(value interface{}) expression {
+	return func(map[string]camundaclientgo.Variable) (interface{}, error) {
+		return value, nil
+	}
+}
+
+// logical returns `left || right` if or is true, otherwise `left && right`, the right operand is evaluated lazily
+func logical(left, right expression, or bool) expression {
+	return func(variables map[string]camundaclientgo.Variable) (interface{}, error) {
+		for _, operand := range []expression{left, right} {
+			value, err := operand(variables)
+			if err != nil {
+				return nil, err
+			}
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("cannot coerce %v to Boolean", value)
+			}
+			if b == or {
+				return or, nil
+			}
+		}
+
+		return !or, nil
+	}
+}
+
+func compare(operator string, a, b interface{}) (interface{}, error) {
+	switch operator {
+	case "==":
+		return reflect.DeepEqual(a, b), nil
+	case "!=":
+		return !reflect.DeepEqual(a, b), nil
+	}
+
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			break
+		}
+		switch operator {
+		case "<":
+			return x < y, nil
+		case ">":
+			return x > y, nil
+		case "<=":
+			return x <= y, nil
+		default:
+			return x >= y, nil
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			break
+		}
+		switch operator {
+		case "<":
+			return x < y, nil
+		case ">":
+			return x > y, nil
+		case "<=":
+			return x <= y, nil
+		default:
+			return x >= y, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot compare %v %s %v", a, operator, b)
+}
+
+// normalizeValue converts numbers to float64 as they are decoded from JSON
+func normalizeValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+
+	return value
+}
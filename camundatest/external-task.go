@@ -0,0 +1,503 @@
+package camundatest
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// fetchAndLock locks available tasks, with asyncResponseTimeout the request waits until tasks are available
+func fetchAndLock(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	query := camundaclientgo.QueryFetchAndLock{}
+	if err := readJson(r, &query); err != nil {
+		return nil, err
+	}
+
+	if query.WorkerId == "" {
+		return nil, badRequest("workerId is required")
+	}
+
+	var deadline time.Time
+	if query.AsyncResponseTimeout != nil {
+		deadline = time.Now().Add(time.Duration(*query.AsyncResponseTimeout) * time.Millisecond)
+	}
+
+	for {
+		tasks := e.lockExternalTasks(&query)
+		if len(tasks) > 0 || !time.Now().Before(deadline) {
+			return tasks, nil
+		}
+
+		changed := e.changed
+		e.mu.Unlock()
+		select {
+		case <-changed:
+		case <-time.After(waitInterval):
+		case <-r.Context().Done():
+		}
+		e.mu.Lock()
+
+		if r.Context().Err() != nil {
+			return []*camundaclientgo.ResLockedExternalTask{}, nil
+		}
+	}
+}
+
+// lockExternalTasks locks available tasks of the topics
+func (e *Engine) lockExternalTasks(query *camundaclientgo.QueryFetchAndLock) []*camundaclientgo.ResLockedExternalTask {
+	now := time.Now()
+
+	type candidate struct {
+		task  *ExternalTask
+		topic *camundaclientgo.QueryFetchAndLockTopic
+	}
+
+	var candidates []candidate
+	for _, task := range e.externalTasks {
+		if task.State != ExternalTaskActive || task.Locked(now) || task.HasIncident() || now.Before(task.RetryTime) {
+			continue
+		}
+
+		for _, topic := range query.Topics {
+			if topic.TopicName == task.TopicName && e.matchTopic(task, topic) {
+				candidates = append(candidates, candidate{task: task, topic: topic})
+				break
+			}
+		}
+	}
+
+	if query.UsePriority != nil && *query.UsePriority {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].task.Priority > candidates[j].task.Priority
+		})
+	}
+
+	res := []*camundaclientgo.ResLockedExternalTask{}
+	for _, c := range candidates {
+		if len(res) >= query.MaxTasks {
+			break
+		}
+
+		c.task.WorkerId = query.WorkerId
+		c.task.LockExpirationTime = now.Add(time.Duration(c.topic.LockDuration) * time.Millisecond)
+
+		locked := &camundaclientgo.ResLockedExternalTask{Variables: e.taskVariables(c.task, c.topic.Variables)}
+		c.task.fill(locked)
+		res = append(res, locked)
+	}
+
+	return res
+}
+
+// matchTopic returns true if the task matches filters of the topic
+func (e *Engine) matchTopic(task *ExternalTask, topic *camundaclientgo.QueryFetchAndLockTopic) bool {
+	if topic.BusinessKey != nil && *topic.BusinessKey != task.BusinessKey {
+		return false
+	}
+	if topic.ProcessDefinitionId != nil && *topic.ProcessDefinitionId != task.ProcessDefinitionId {
+		return false
+	}
+	if len(topic.ProcessDefinitionIdIn) > 0 && !contains(topic.ProcessDefinitionIdIn, task.ProcessDefinitionId) {
+		return false
+	}
+	if topic.ProcessDefinitionKey != nil && *topic.ProcessDefinitionKey != task.ProcessDefinitionKey {
+		return false
+	}
+
+	if len(topic.ProcessVariables) > 0 {
+		variables := e.taskVariables(task, nil)
+		for name, value := range topic.ProcessVariables {
+			variable, ok := variables[name]
+			if !ok || !reflect.DeepEqual(normalizeValue(variable.Value), normalizeValue(value)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// externalTaskFilter parameters of external task queries
+type externalTaskFilter struct {
+	ExternalTaskId      *string `json:"externalTaskId"`
+	TopicName           *string `json:"topicName"`
+	WorkerId            *string `json:"workerId"`
+	Locked              *bool   `json:"locked"`
+	NotLocked           *bool   `json:"notLocked"`
+	WithRetriesLeft     *bool   `json:"withRetriesLeft"`
+	NoRetriesLeft       *bool   `json:"noRetriesLeft"`
+	ActivityId          *string `json:"activityId"`
+	ProcessInstanceId   *string `json:"processInstanceId"`
+	ProcessDefinitionId *string `json:"processDefinitionId"`
+}
+
+func queryExternalTaskFilter(query url.Values) *externalTaskFilter {
+	filter := &externalTaskFilter{}
+	for name, field := range map[string]**string{
+		"externalTaskId":      &filter.ExternalTaskId,
+		"topicName":           &filter.TopicName,
+		"workerId":            &filter.WorkerId,
+		"activityId":          &filter.ActivityId,
+		"processInstanceId":   &filter.ProcessInstanceId,
+		"processDefinitionId": &filter.ProcessDefinitionId,
+	} {
+		if value := query.Get(name); value != "" {
+			*field = &value
+		}
+	}
+
+	for name, field := range map[string]**bool{
+		"locked":          &filter.Locked,
+		"notLocked":       &filter.NotLocked,
+		"withRetriesLeft": &filter.WithRetriesLeft,
+		"noRetriesLeft":   &filter.NoRetriesLeft,
+	} {
+		if value, err := strconv.ParseBool(query.Get(name)); err == nil {
+			*field = &value
+		}
+	}
+
+	return filter
+}
+
+func (f *externalTaskFilter) match(task *ExternalTask, now time.Time) bool {
+	equal := func(filter *string, value string) bool {
+		return filter == nil || *filter == value
+	}
+	is := func(filter *bool, value bool) bool {
+		return filter == nil || !*filter || value
+	}
+
+	return equal(f.ExternalTaskId, task.Id) &&
+		equal(f.TopicName, task.TopicName) &&
+		equal(f.WorkerId, task.WorkerId) &&
+		equal(f.ActivityId, task.ActivityId) &&
+		equal(f.ProcessInstanceId, task.ProcessInstanceId) &&
+		equal(f.ProcessDefinitionId, task.ProcessDefinitionId) &&
+		is(f.Locked, task.Locked(now)) &&
+		is(f.NotLocked, !task.Locked(now)) &&
+		is(f.WithRetriesLeft, !task.HasIncident()) &&
+		is(f.NoRetriesLeft, task.HasIncident())
+}
+
+// findExternalTasks returns active tasks matching the filter
+func (e *Engine) findExternalTasks(filter *externalTaskFilter) []*ExternalTask {
+	now := time.Now()
+
+	var result []*ExternalTask
+	for _, task := range e.externalTasks {
+		if task.State == ExternalTaskActive && filter.match(task, now) {
+			result = append(result, task)
+		}
+	}
+
+	return result
+}
+
+func externalTasksResponse(r *http.Request, tasks []*ExternalTask) []*camundaclientgo.ResExternalTask {
+	first, last := page(r, len(tasks))
+
+	res := []*camundaclientgo.ResExternalTask{}
+	for _, task := range tasks[first:last] {
+		res = append(res, task.response())
+	}
+
+	return res
+}
+
+func getExternalTasks(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return externalTasksResponse(r, e.findExternalTasks(queryExternalTaskFilter(r.URL.Query()))), nil
+}
+
+func getExternalTasksPost(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	filter := &externalTaskFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	return externalTasksResponse(r, e.findExternalTasks(filter)), nil
+}
+
+func getExternalTasksCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findExternalTasks(queryExternalTaskFilter(r.URL.Query())))}, nil
+}
+
+func getExternalTasksCountPost(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	filter := &externalTaskFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	return &camundaclientgo.ResCount{Count: len(e.findExternalTasks(filter))}, nil
+}
+
+func (e *Engine) getExternalTask(id string) (*ExternalTask, error) {
+	task := e.externalTask(id)
+	if task == nil || task.State != ExternalTaskActive {
+		return nil, notFound("External task with id %s does not exist", id)
+	}
+
+	return task, nil
+}
+
+// getLockedExternalTask returns the task if it is locked by the worker
+func (e *Engine) getLockedExternalTask(id string, workerId *string, action string) (*ExternalTask, error) {
+	task, err := e.getExternalTask(id)
+	if err != nil {
+		return nil, err
+	}
+
+	worker := ""
+	if workerId != nil {
+		worker = *workerId
+	}
+	if task.WorkerId == "" || task.WorkerId != worker {
+		return nil, badUserRequest("External Task %s cannot be %s by worker '%s'. It is locked by worker '%s'.", id, action, worker, task.WorkerId)
+	}
+
+	return task, nil
+}
+
+func getExternalTask(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	task, err := e.getExternalTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return task.response(), nil
+}
+
+func completeExternalTask(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	req := camundaclientgo.QueryComplete{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	task, err := e.getLockedExternalTask(params[0], req.WorkerId, "completed")
+	if err != nil {
+		return nil, err
+	}
+
+	var variables, localVariables map[string]camundaclientgo.Variable
+	if req.Variables != nil {
+		variables = *req.Variables
+	}
+	if req.LocalVariables != nil {
+		localVariables = *req.LocalVariables
+	}
+
+	if err := e.completeExternalTask(task, variables, localVariables); err != nil {
+		return nil, engineFailure(err)
+	}
+
+	return nil, nil
+}
+
+func handleExternalTaskFailure(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	req := camundaclientgo.QueryHandleFailure{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	task, err := e.getLockedExternalTask(params[0], req.WorkerId, "failed")
+	if err != nil {
+		return nil, err
+	}
+
+	retries := 0
+	if req.Retries != nil {
+		retries = *req.Retries
+	}
+	task.Retries = &retries
+	task.Failures++
+	task.ErrorMessage, task.ErrorDetails = "", ""
+	if req.ErrorMessage != nil {
+		task.ErrorMessage = *req.ErrorMessage
+	}
+	if req.ErrorDetails != nil {
+		task.ErrorDetails = *req.ErrorDetails
+	}
+
+	task.LockExpirationTime = time.Time{}
+	task.RetryTime = time.Time{}
+	if req.RetryTimeout != nil {
+		task.RetryTime = time.Now().Add(time.Duration(*req.RetryTimeout) * time.Millisecond)
+	}
+
+	return nil, nil
+}
+
+func handleExternalTaskBPMNError(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	req := camundaclientgo.QueryHandleBPMNError{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	task, err := e.getLockedExternalTask(params[0], req.WorkerId, "reported a BPMN error")
+	if err != nil {
+		return nil, err
+	}
+
+	errorCode := ""
+	if req.ErrorCode != nil {
+		errorCode = *req.ErrorCode
+	}
+	var variables map[string]camundaclientgo.Variable
+	if req.Variables != nil {
+		variables = *req.Variables
+	}
+
+	if err := e.handleBPMNError(task, errorCode, variables); err != nil {
+		return nil, engineFailure(err)
+	}
+	if req.ErrorMessage != nil {
+		task.ErrorMessage = *req.ErrorMessage
+	}
+
+	return nil, nil
+}
+
+func handleExternalTaskEscalation(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	req := camundaclientgo.QueryHandleEscalation{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	task, err := e.getLockedExternalTask(params[0], req.WorkerId, "reported an escalation")
+	if err != nil {
+		return nil, err
+	}
+
+	escalationCode := ""
+	if req.EscalationCode != nil {
+		escalationCode = *req.EscalationCode
+	}
+	var variables map[string]camundaclientgo.Variable
+	if req.Variables != nil {
+		variables = *req.Variables
+	}
+
+	if err := e.handleEscalation(task, escalationCode, variables); err != nil {
+		return nil, engineFailure(err)
+	}
+
+	return nil, nil
+}
+
+func extendExternalTaskLock(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	req := camundaclientgo.QueryExtendLock{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	task, err := e.getLockedExternalTask(params[0], req.WorkerId, "extended")
+	if err != nil {
+		return nil, err
+	}
+	if !task.Locked(time.Now()) {
+		return nil, badUserRequest("Cannot extend a lock that expired")
+	}
+
+	duration := 0
+	if req.NewDuration != nil {
+		duration = *req.NewDuration
+	}
+	task.LockExpirationTime = time.Now().Add(time.Duration(duration) * time.Millisecond)
+
+	return nil, nil
+}
+
+func unlockExternalTask(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	task, err := e.getExternalTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	task.WorkerId = ""
+	task.LockExpirationTime = time.Time{}
+
+	return nil, nil
+}
+
+func setExternalTaskRetries(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	task, err := e.getExternalTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := struct {
+		Retries *int `json:"retries"`
+	}{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Retries == nil || *req.Retries < 0 {
+		return nil, badRequest("The number of retries cannot be negative or null.")
+	}
+	task.Retries = req.Retries
+
+	return nil, nil
+}
+
+func setExternalTaskPriority(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	task, err := e.getExternalTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := struct {
+		Priority int `json:"priority"`
+	}{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+	task.Priority = req.Priority
+
+	return nil, nil
+}
+
+func (t *ExternalTask) fill(res *camundaclientgo.ResLockedExternalTask) {
+	res.ActivityId = t.ActivityId
+	res.ActivityInstanceId = t.ActivityId + ":" + t.Id
+	res.ErrorMessage = t.ErrorMessage
+	res.ErrorDetails = t.ErrorDetails
+	res.ExecutionId = t.ProcessInstanceId
+	res.Id = t.Id
+	res.LockExpirationTime = formatTime(t.LockExpirationTime)
+	res.ProcessDefinitionId = t.ProcessDefinitionId
+	res.ProcessDefinitionKey = t.ProcessDefinitionKey
+	res.ProcessInstanceId = t.ProcessInstanceId
+	res.Retries = t.Retries
+	res.WorkerId = t.WorkerId
+	res.Priority = t.Priority
+	res.TopicName = t.TopicName
+	res.BusinessKey = t.BusinessKey
+}
+
+func (t *ExternalTask) response() *camundaclientgo.ResExternalTask {
+	locked := &camundaclientgo.ResLockedExternalTask{}
+	t.fill(locked)
+
+	return &camundaclientgo.ResExternalTask{
+		ActivityId:           locked.ActivityId,
+		ActivityInstanceId:   locked.ActivityInstanceId,
+		ErrorMessage:         locked.ErrorMessage,
+		ErrorDetails:         locked.ErrorDetails,
+		ExecutionId:          locked.ExecutionId,
+		Id:                   locked.Id,
+		LockExpirationTime:   locked.LockExpirationTime,
+		ProcessDefinitionId:  locked.ProcessDefinitionId,
+		ProcessDefinitionKey: locked.ProcessDefinitionKey,
+		ProcessInstanceId:    locked.ProcessInstanceId,
+		Retries:              locked.Retries,
+		WorkerId:             locked.WorkerId,
+		Priority:             locked.Priority,
+		TopicName:            locked.TopicName,
+		BusinessKey:          locked.BusinessKey,
+	}
+}
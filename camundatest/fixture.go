@@ -0,0 +1,444 @@
+package camundatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// RecordEnv an environment variable, if it is not empty UseFixture records fixtures instead of replaying them
+const RecordEnv = "CAMUNDA_RECORD"
+
+// Interaction a recorded pair of a request to the engine and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest a request to the engine. The body is normalized: JSON is re-encoded with sorted keys,
+// parts of multipart forms are sorted by name and other bodies are encoded as a JSON string.
+// Headers are not recorded, so credentials never get into fixtures
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  url.Values      `json:"query,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// RecordedResponse a response of the engine, the body is stored as JSON, text or binary data
+type RecordedResponse struct {
+	StatusCode  int             `json:"statusCode"`
+	ContentType string          `json:"contentType,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+	Binary      []byte          `json:"binary,omitempty"`
+}
+
+// multipartPart a normalized part of a multipart form
+type multipartPart struct {
+	Name     string `json:"name"`
+	FileName string `json:"fileName,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Recorder a http transport which records requests and responses to a fixture file
+type Recorder struct {
+	file string
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder returns a transport which sends requests by next (default: http.DefaultTransport) and records them.
+// Recorded interactions are written to the file by Save
+func NewRecorder(file string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Recorder{file: file, next: next}
+}
+
+// RoundTrip sends the request and records it with the response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := newRecordedRequest(req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(data))
+	res, err := r.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readBody(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Request:  recorded,
+		Response: newRecordedResponse(res, body),
+	})
+	r.mu.Unlock()
+
+	return res, nil
+}
+
+// Interactions returns the recorded interactions
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Interaction{}, r.interactions...)
+}
+
+// Save writes the recorded interactions to the fixture file, missing directories are created
+func (r *Recorder) Save() error {
+	data, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode fixture: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.file), 0750); err != nil {
+		return fmt.Errorf("can't create directory of fixture: %w", err)
+	}
+
+	return ioutil.WriteFile(r.file, append(data, '\n'), 0600)
+}
+
+// Replayer a http transport which serves responses recorded by Recorder without network
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	served       []bool
+}
+
+// NewReplayer returns a transport which serves the interactions of the fixture file
+func NewReplayer(file string) (*Replayer, error) {
+	// #nosec G304 The fixture file is chosen by the test
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("can't read fixture: %w", err)
+	}
+
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("can't decode fixture %s: %w", file, err)
+	}
+
+	return NewReplayerFromInteractions(interactions)
+}
+
+// NewReplayerFromInteractions returns a transport which serves the interactions, e.g. of Recorder.Interactions
+func NewReplayerFromInteractions(interactions []Interaction) (*Replayer, error) {
+	replayer := &Replayer{
+		interactions: make([]Interaction, len(interactions)),
+		served:       make([]bool, len(interactions)),
+	}
+
+	for i, interaction := range interactions {
+		if len(interaction.Request.Body) > 0 {
+			body, err := canonicalJson(interaction.Request.Body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid body of recorded request %s %s: %w",
+					interaction.Request.Method, interaction.Request.Path, err)
+			}
+			interaction.Request.Body = body
+		}
+		replayer.interactions[i] = interaction
+	}
+
+	return replayer, nil
+}
+
+// RoundTrip serves the response of the first interaction which matches the method, path, query and
+// normalized body of the request and was not served yet. If all matching interactions are served,
+// the last of them is served again, e.g. for polling. A request without a matching interaction fails
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := newRecordedRequest(req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := -1
+	for i, interaction := range r.interactions {
+		if !interaction.Request.matches(recorded) {
+			continue
+		}
+
+		match = i
+		if !r.served[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("camundatest: no recorded interaction matches %s %s?%s with body %s",
+			recorded.Method, recorded.Path, recorded.Query.Encode(), recorded.Body)
+	}
+
+	r.served[match] = true
+	return r.interactions[match].Response.response(req)
+}
+
+// Unserved returns the interactions which were not served yet
+func (r *Replayer) Unserved() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []Interaction
+	for i, interaction := range r.interactions {
+		if !r.served[i] {
+			result = append(result, interaction)
+		}
+	}
+
+	return result
+}
+
+// AssertAllServed asserts that all recorded interactions were served, i.e. the tested code sent all requests
+// of the fixture
+func (r *Replayer) AssertAllServed(tb testing.TB) bool {
+	tb.Helper()
+
+	unserved := r.Unserved()
+	for _, interaction := range unserved {
+		tb.Errorf("recorded request %s %s was not sent", interaction.Request.Method, interaction.Request.Path)
+	}
+
+	return len(unserved) == 0
+}
+
+// UseFixture sets a transport of the client which replays the fixture file. If the environment variable
+// CAMUNDA_RECORD is not empty, requests are sent to the engine by http.DefaultTransport instead and recorded
+// to the fixture file at the end of the test, unless the test failed
+func UseFixture(tb testing.TB, client *camundaclientgo.Client, file string) {
+	tb.Helper()
+
+	if os.Getenv(RecordEnv) != "" {
+		recorder := NewRecorder(file, nil)
+		client.SetCustomTransport(recorder)
+		tb.Cleanup(func() {
+			if tb.Failed() {
+				return
+			}
+
+			if err := recorder.Save(); err != nil {
+				tb.Errorf("can't save fixture: %s", err)
+			}
+		})
+		return
+	}
+
+	replayer, err := NewReplayer(file)
+	if err != nil {
+		tb.Fatalf("%s, run the test with %s=1 against an engine to record it", err, RecordEnv)
+	}
+	client.SetCustomTransport(replayer)
+}
+
+func newRecordedRequest(req *http.Request, body []byte) (RecordedRequest, error) {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+	}
+
+	if query := req.URL.Query(); len(query) > 0 {
+		recorded.Query = query
+	}
+
+	normalized, err := normalizeBody(req.Header.Get("Content-Type"), body)
+	if err != nil {
+		return RecordedRequest{}, fmt.Errorf("can't normalize body of %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	recorded.Body = normalized
+
+	return recorded, nil
+}
+
+func (r *RecordedRequest) matches(other RecordedRequest) bool {
+	return r.Method == other.Method &&
+		r.Path == other.Path &&
+		reflect.DeepEqual(r.Query, other.Query) &&
+		bytes.Equal(r.Body, other.Body)
+}
+
+// normalizeBody returns the body as JSON which does not depend on the order of keys, formatting or
+// multipart boundaries
+func normalizeBody(contentType string, body []byte) (json.RawMessage, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "multipart/") {
+		parts, err := normalizeMultipart(body, params["boundary"])
+		if err != nil {
+			return nil, err
+		}
+
+		return canonicalJson(parts)
+	}
+
+	if normalized, err := canonicalJson(body); err == nil {
+		return normalized, nil
+	}
+
+	return encodeJson(string(body))
+}
+
+func normalizeMultipart(body []byte, boundary string) ([]byte, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+
+	parts := []multipartPart{}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, multipartPart{
+			Name:     part.FormName(),
+			FileName: part.FileName(),
+			Content:  string(content),
+		})
+	}
+
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].Name < parts[j].Name
+	})
+
+	return encodeJson(parts)
+}
+
+// canonicalJson re-encodes JSON compact with sorted keys
+func canonicalJson(data []byte) (json.RawMessage, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return encodeJson(value)
+}
+
+// encodeJson encodes the value without escaping of HTML to keep fixtures readable
+func encodeJson(value interface{}) (json.RawMessage, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func newRecordedResponse(res *http.Response, body []byte) RecordedResponse {
+	recorded := RecordedResponse{
+		StatusCode:  res.StatusCode,
+		ContentType: res.Header.Get("Content-Type"),
+	}
+
+	switch {
+	case len(body) == 0:
+	case strings.Contains(recorded.ContentType, "json") && json.Valid(body):
+		recorded.Body = body
+	case utf8.Valid(body):
+		recorded.Text = string(body)
+	default:
+		recorded.Binary = body
+	}
+
+	return recorded
+}
+
+func (r *RecordedResponse) response(req *http.Request) (*http.Response, error) {
+	var body []byte
+	switch {
+	case len(r.Body) > 0:
+		buf := &bytes.Buffer{}
+		if err := json.Compact(buf, r.Body); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	case r.Text != "":
+		body = []byte(r.Text)
+	default:
+		body = r.Binary
+	}
+
+	header := http.Header{}
+	if r.ContentType != "" {
+		header.Set("Content-Type", r.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
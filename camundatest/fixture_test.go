@@ -0,0 +1,156 @@
+package camundatest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// helloWorld deploys and runs the hello world process through the client, returns the completed task
+func helloWorld(t *testing.T, client *camundaclientgo.Client, printed string) (*camundaclientgo.ResLockedExternalTask, error) {
+	file, err := os.Open("../examples/deployment/HelloWorld.bpmn")
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = client.Deployment.Create(camundaclientgo.ReqDeploymentCreate{
+		DeploymentName: "HelloWorldProcessDemo",
+		Resources:      map[string]interface{}{"HelloWorld.bpmn": file},
+	})
+	require.NoError(t, err)
+
+	key := "hello-world-process"
+	_, err = client.ProcessDefinition.StartInstance(
+		camundaclientgo.QueryProcessDefinitionBy{Key: &key},
+		camundaclientgo.ReqStartInstance{Variables: &map[string]camundaclientgo.Variable{
+			"isWorld": {Value: true, Type: "Boolean"},
+		}},
+	)
+	require.NoError(t, err)
+
+	tasks, err := client.ExternalTask.FetchAndLock(camundaclientgo.QueryFetchAndLock{
+		WorkerId: "worker",
+		MaxTasks: 10,
+		Topics:   []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "PrintWorld", LockDuration: 1000}},
+	})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+
+	worker := "worker"
+	return tasks[0], client.ExternalTask.Complete(tasks[0].Id, camundaclientgo.QueryComplete{
+		WorkerId:  &worker,
+		Variables: &map[string]camundaclientgo.Variable{"printed": {Value: printed, Type: "String"}},
+	})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "camundatest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "fixtures", "hello-world.json")
+
+	engine := NewEngine()
+	client := engine.Client()
+	recorder := NewRecorder(file, nil)
+	client.SetCustomTransport(recorder)
+
+	recorded, err := helloWorld(t, client, "world")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save())
+	engine.Close()
+	assert.Len(t, recorder.Interactions(), 4)
+
+	t.Run("replay", func(t *testing.T) {
+		client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: engine.URL()})
+		replayer, err := NewReplayer(file)
+		require.NoError(t, err)
+		client.SetCustomTransport(replayer)
+
+		replayed, err := helloWorld(t, client, "world")
+		require.NoError(t, err)
+		assert.Equal(t, recorded, replayed)
+		replayer.AssertAllServed(t)
+	})
+
+	t.Run("changed request", func(t *testing.T) {
+		client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: engine.URL()})
+		UseFixture(t, client, file)
+
+		_, err := helloWorld(t, client, "hello")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no recorded interaction matches POST /engine-rest/external-task/")
+	})
+}
+
+func TestReplayErrorResponses(t *testing.T) {
+	client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: "http://localhost:1/engine-rest"})
+	replayer, err := NewReplayerFromInteractions([]Interaction{
+		{
+			Request: RecordedRequest{Method: "GET", Path: "/engine-rest/process-instance/unknown"},
+			Response: RecordedResponse{
+				StatusCode:  404,
+				ContentType: "application/json",
+				Body:        []byte(`{"type": "InvalidRequestException", "message": "not found"}`),
+			},
+		},
+		{
+			Request:  RecordedRequest{Method: "GET", Path: "/engine-rest/process-instance/broken"},
+			Response: RecordedResponse{StatusCode: 500, ContentType: "text/plain", Text: "failure"},
+		},
+	})
+	require.NoError(t, err)
+	client.SetCustomTransport(replayer)
+
+	_, err = client.ProcessInstance.Get("unknown")
+	assert.Equal(t, camundaclientgo.ErrorNotFound, err)
+
+	_, err = client.ProcessInstance.Get("broken")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failure")
+}
+
+// TestReplayEscalation pins the endpoint of escalations, the engine has no /escalation endpoint
+func TestReplayEscalation(t *testing.T) {
+	client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: "http://localhost:1/engine-rest"})
+	replayer, err := NewReplayerFromInteractions([]Interaction{
+		{
+			Request: RecordedRequest{
+				Method: "POST",
+				Path:   "/engine-rest/external-task/anExternalTaskId/bpmnEscalation",
+				Body:   []byte(`{"workerId": "aWorker", "escalationCode": "12345", "variables": {"aVariable": {"value": "aStringValue", "type": "String", "valueInfo": {"objectTypeName": null, "serializationDataFormat": null}}}}`),
+			},
+			Response: RecordedResponse{StatusCode: 204},
+		},
+	})
+	require.NoError(t, err)
+	client.SetCustomTransport(replayer)
+
+	workerId, escalationCode := "aWorker", "12345"
+	require.NoError(t, client.ExternalTask.HandleEscalation("anExternalTaskId", camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &workerId,
+		EscalationCode: &escalationCode,
+		Variables:      &map[string]camundaclientgo.Variable{"aVariable": {Value: "aStringValue", Type: "String"}},
+	}))
+	replayer.AssertAllServed(t)
+}
+
+func TestNormalizeBody(t *testing.T) {
+	a, err := normalizeBody("application/json", []byte(`{"b": [1, 2.50], "a": "<x>"}`))
+	require.NoError(t, err)
+	b, err := normalizeBody("application/json", []byte(`{"a":"<x>","b":[1,2.50]}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":"<x>","b":[1,2.50]}`, string(a))
+	assert.Equal(t, a, b)
+
+	text, err := normalizeBody("text/plain", []byte("plain text"))
+	require.NoError(t, err)
+	assert.Equal(t, `"plain text"`, string(text))
+
+	empty, err := normalizeBody("application/json", nil)
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+}
@@ -0,0 +1,287 @@
+package camundatest
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func (e *Engine) findHistoryProcessInstances(r *http.Request) []*ProcessInstance {
+	query := r.URL.Query()
+	ids := splitList(query.Get("processInstanceIds"))
+
+	var result []*ProcessInstance
+	for _, instance := range e.instances {
+		if (query.Get("processInstanceId") != "" && instance.Id != query.Get("processInstanceId")) ||
+			(len(ids) > 0 && !contains(ids, instance.Id)) ||
+			(query.Get("processInstanceBusinessKey") != "" && instance.BusinessKey != query.Get("processInstanceBusinessKey")) ||
+			(query.Get("processDefinitionId") != "" && instance.DefinitionId != query.Get("processDefinitionId")) ||
+			(query.Get("processDefinitionKey") != "" && instance.DefinitionKey != query.Get("processDefinitionKey")) ||
+			(query.Get("finished") == "true" && instance.State == ProcessInstanceActive) ||
+			(query.Get("unfinished") == "true" && instance.State != ProcessInstanceActive) {
+			continue
+		}
+		result = append(result, instance)
+	}
+
+	return result
+}
+
+func getHistoryProcessInstances(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	instances := e.findHistoryProcessInstances(r)
+	first, last := page(r, len(instances))
+
+	res := []*camundaclientgo.ResHistoryProcessInstance{}
+	for _, instance := range instances[first:last] {
+		res = append(res, instance.historyResponse())
+	}
+
+	return res, nil
+}
+
+func getHistoryProcessInstancesCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findHistoryProcessInstances(r))}, nil
+}
+
+func getHistoryProcessInstance(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance := e.instance(params[0])
+	if instance == nil {
+		return nil, notFound("Historic process instance with id %s does not exist", params[0])
+	}
+
+	return instance.historyResponse(), nil
+}
+
+// historyVariable a variable of a process instance, its id is the id of the instance and the name
+type historyVariable struct {
+	instance *ProcessInstance
+	name     string
+}
+
+func (v *historyVariable) id() string {
+	return v.instance.Id + ":" + v.name
+}
+
+func (e *Engine) findHistoryVariables(r *http.Request) []*historyVariable {
+	query := r.URL.Query()
+	instanceIds := splitList(query.Get("processInstanceIdIn"))
+	if id := query.Get("processInstanceId"); id != "" {
+		instanceIds = append(instanceIds, id)
+	}
+
+	var result []*historyVariable
+	for _, instance := range e.instances {
+		if len(instanceIds) > 0 && !contains(instanceIds, instance.Id) {
+			continue
+		}
+
+		names := make([]string, 0, len(instance.Variables))
+		for name := range instance.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if (query.Get("variableName") != "" && name != query.Get("variableName")) ||
+				(query.Get("variableNameLike") != "" && !matchLike(name, query.Get("variableNameLike"))) {
+				continue
+			}
+			result = append(result, &historyVariable{instance: instance, name: name})
+		}
+	}
+
+	return result
+}
+
+// matchLike matches the value by a pattern of a like query, `%` is a wildcard
+func matchLike(value, pattern string) bool {
+	parts := strings.Split(pattern, "%")
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 {
+			return strings.HasSuffix(value, part)
+		}
+
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+
+	return value == ""
+}
+
+func getHistoryVariableInstances(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	variables := e.findHistoryVariables(r)
+	first, last := page(r, len(variables))
+
+	res := []*camundaclientgo.ResHistoryVariableInstance{}
+	for _, variable := range variables[first:last] {
+		res = append(res, variable.response())
+	}
+
+	return res, nil
+}
+
+func getHistoryVariableInstancesCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findHistoryVariables(r))}, nil
+}
+
+func getHistoryVariableInstance(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	separator := strings.LastIndex(params[0], ":")
+	if separator > 0 {
+		instance := e.instance(params[0][:separator])
+		name := params[0][separator+1:]
+		if _, ok := instance.variable(name); ok {
+			return (&historyVariable{instance: instance, name: name}).response(), nil
+		}
+	}
+
+	return nil, notFound("Historic variable instance with Id '%s' does not exist", params[0])
+}
+
+func (v *historyVariable) response() *camundaclientgo.ResHistoryVariableInstance {
+	variable := v.instance.Variables[v.name]
+	res := &camundaclientgo.ResHistoryVariableInstance{
+		Id:                   v.id(),
+		Name:                 v.name,
+		Type:                 variable.Type,
+		Value:                variable.Value,
+		ProcessDefinitionKey: v.instance.DefinitionKey,
+		ProcessDefinitionId:  v.instance.DefinitionId,
+		ProcessInstanceId:    v.instance.Id,
+		ExecutionId:          v.instance.Id,
+		ActivityInstanceId:   v.instance.Id,
+	}
+	if variable.ValueInfo.ObjectTypeName != nil {
+		res.ValueInfo.ObjectTypeName = *variable.ValueInfo.ObjectTypeName
+	}
+	if variable.ValueInfo.SerializationDataFormat != nil {
+		res.ValueInfo.SerializationDataFormat = *variable.ValueInfo.SerializationDataFormat
+	}
+
+	return res
+}
+
+func (i *ProcessInstance) variable(name string) (camundaclientgo.Variable, bool) {
+	if i == nil {
+		return camundaclientgo.Variable{}, false
+	}
+
+	variable, ok := i.Variables[name]
+	return variable, ok
+}
+
+func (i *ProcessInstance) historyResponse() *camundaclientgo.ResHistoryProcessInstance {
+	res := &camundaclientgo.ResHistoryProcessInstance{
+		Id:                       i.Id,
+		RootProcessInstanceId:    i.Id,
+		ProcessDefinitionName:    i.definition.Name,
+		ProcessDefinitionKey:     i.DefinitionKey,
+		ProcessDefinitionVersion: i.definition.Version,
+		ProcessDefinitionId:      i.DefinitionId,
+		BusinessKey:              i.BusinessKey,
+		StartTime:                formatTime(i.StartTime),
+		EndTime:                  formatTime(i.EndTime),
+		StartActivityId:          i.StartActivityId,
+		State:                    string(i.State),
+	}
+	if !i.EndTime.IsZero() {
+		res.DurationInMillis = float32(i.EndTime.Sub(i.StartTime).Milliseconds())
+	}
+
+	return res
+}
+
+// historyUserTaskFilter parameters of historic user task queries
+type historyUserTaskFilter struct {
+	TaskId               string `json:"taskId"`
+	ProcessInstanceId    string `json:"processInstanceId"`
+	ProcessDefinitionId  string `json:"processDefinitionId"`
+	ProcessDefinitionKey string `json:"processDefinitionKey"`
+	TaskDefinitionKey    string `json:"taskDefinitionKey"`
+	TaskAssignee         string `json:"taskAssignee"`
+	Finished             bool   `json:"finished"`
+	Unfinished           bool   `json:"unfinished"`
+}
+
+func (e *Engine) findHistoryUserTasks(r *http.Request) ([]*UserTask, error) {
+	filter := &historyUserTaskFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	var result []*UserTask
+	for _, task := range e.userTasks {
+		if (filter.TaskId != "" && task.Id != filter.TaskId) ||
+			(filter.ProcessInstanceId != "" && task.ProcessInstanceId != filter.ProcessInstanceId) ||
+			(filter.ProcessDefinitionId != "" && task.ProcessDefinitionId != filter.ProcessDefinitionId) ||
+			(filter.ProcessDefinitionKey != "" && task.ProcessDefinitionKey != filter.ProcessDefinitionKey) ||
+			(filter.TaskDefinitionKey != "" && task.TaskDefinitionKey != filter.TaskDefinitionKey) ||
+			(filter.TaskAssignee != "" && task.Assignee != filter.TaskAssignee) ||
+			(filter.Finished && task.State == UserTaskActive) ||
+			(filter.Unfinished && task.State != UserTaskActive) {
+			continue
+		}
+		result = append(result, task)
+	}
+
+	return result, nil
+}
+
+func getHistoryUserTasks(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	tasks, err := e.findHistoryUserTasks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := page(r, len(tasks))
+	res := []*camundaclientgo.HistoryTaskInstanceResponse{}
+	for _, task := range tasks[first:last] {
+		res = append(res, task.historyResponse())
+	}
+
+	return res, nil
+}
+
+func getHistoryUserTasksCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	tasks, err := e.findHistoryUserTasks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &camundaclientgo.ResCount{Count: len(tasks)}, nil
+}
+
+func (t *UserTask) historyResponse() *camundaclientgo.HistoryTaskInstanceResponse {
+	res := &camundaclientgo.HistoryTaskInstanceResponse{
+		Id:                    t.Id,
+		Name:                  t.Name,
+		Assignee:              t.Assignee,
+		ExecutionId:           t.ProcessInstanceId,
+		Priority:              50,
+		ProcessDefinitionId:   t.ProcessDefinitionId,
+		ProcessInstanceId:     t.ProcessInstanceId,
+		TaskDefinitionKey:     t.TaskDefinitionKey,
+		StartTime:             formatTime(t.Created),
+		EndTime:               formatTime(t.EndTime),
+		RootProcessInstanceId: t.ProcessInstanceId,
+	}
+	if !t.EndTime.IsZero() {
+		res.Duration = t.EndTime.Sub(t.Created).Milliseconds()
+	}
+	if t.State == UserTaskDeleted {
+		res.TaskDeleteReason = "deleted"
+	} else if t.State == UserTaskCompleted {
+		res.TaskDeleteReason = "completed"
+	}
+
+	return res
+}
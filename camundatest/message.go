@@ -0,0 +1,26 @@
+package camundatest
+
+import (
+	"net/http"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func correlateMessage(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	req := camundaclientgo.ReqMessage{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	message := &Message{Name: req.MessageName, BusinessKey: req.BusinessKey}
+	if req.ProcessVariables != nil {
+		message.Variables = copyVariables(*req.ProcessVariables)
+	}
+	e.messages = append(e.messages, message)
+
+	if err := e.correlateMessage(message); err != nil {
+		return nil, badRequest("%s", err)
+	}
+
+	return nil, nil
+}
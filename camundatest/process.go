@@ -0,0 +1,416 @@
+package camundatest
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func (e *Engine) findProcessDefinitions(r *http.Request) []*ProcessDefinition {
+	query := r.URL.Query()
+
+	var result []*ProcessDefinition
+	for _, definition := range e.definitions {
+		if (query.Get("processDefinitionId") != "" && definition.Id != query.Get("processDefinitionId")) ||
+			(query.Get("key") != "" && definition.Key != query.Get("key")) ||
+			(query.Get("name") != "" && definition.Name != query.Get("name")) ||
+			(query.Get("deploymentId") != "" && definition.DeploymentId != query.Get("deploymentId")) ||
+			(query.Get("version") != "" && strconv.Itoa(definition.Version) != query.Get("version")) ||
+			(query.Get("latestVersion") == "true" && definition != e.latestDefinition(definition.Key)) {
+			continue
+		}
+		result = append(result, definition)
+	}
+
+	return result
+}
+
+func getProcessDefinitions(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	definitions := e.findProcessDefinitions(r)
+	first, last := page(r, len(definitions))
+
+	res := []*camundaclientgo.ResProcessDefinition{}
+	for _, definition := range definitions[first:last] {
+		res = append(res, definition.response())
+	}
+
+	return res, nil
+}
+
+func getProcessDefinitionsCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findProcessDefinitions(r))}, nil
+}
+
+func (e *Engine) getProcessDefinitionByKey(key string) (*ProcessDefinition, error) {
+	definition := e.latestDefinition(key)
+	if definition == nil {
+		return nil, notFound("No matching process definition with key: %s and no tenant-id", key)
+	}
+
+	return definition, nil
+}
+
+func (e *Engine) getProcessDefinition(id string) (*ProcessDefinition, error) {
+	definition := e.definition(id)
+	if definition == nil {
+		return nil, notFound("No matching definition with id %s", id)
+	}
+
+	return definition, nil
+}
+
+func getProcessDefinitionByKey(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinitionByKey(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return definition.response(), nil
+}
+
+func getProcessDefinition(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinition(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return definition.response(), nil
+}
+
+func getProcessDefinitionXmlByKey(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinitionByKey(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return e.processDefinitionXml(definition), nil
+}
+
+func getProcessDefinitionXml(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinition(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return e.processDefinitionXml(definition), nil
+}
+
+func (e *Engine) processDefinitionXml(definition *ProcessDefinition) *camundaclientgo.ResBPMNProcessDefinition {
+	res := &camundaclientgo.ResBPMNProcessDefinition{Id: definition.Id}
+	for _, deployment := range e.deployments {
+		if deployment.Id != definition.DeploymentId {
+			continue
+		}
+		for _, resource := range deployment.Resources {
+			if resource.Name == definition.Resource {
+				res.Bpmn20Xml = string(resource.Data)
+			}
+		}
+	}
+
+	return res
+}
+
+func startProcessInstanceByKey(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinitionByKey(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return e.startInstanceByRequest(definition, r)
+}
+
+func startProcessInstance(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	definition, err := e.getProcessDefinition(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return e.startInstanceByRequest(definition, r)
+}
+
+func (e *Engine) startInstanceByRequest(definition *ProcessDefinition, r *http.Request) (interface{}, error) {
+	req := camundaclientgo.ReqStartInstance{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	var variables map[string]camundaclientgo.Variable
+	if req.Variables != nil {
+		variables = *req.Variables
+	}
+	businessKey := ""
+	if req.BusinessKey != nil {
+		businessKey = *req.BusinessKey
+	}
+
+	instance, err := e.startInstance(definition, definition.process.noneStartEvent(), businessKey, variables)
+	if err != nil {
+		return nil, engineFailure(err)
+	}
+
+	res := &camundaclientgo.ResStartedProcessDefinition{
+		Id:           instance.Id,
+		DefinitionId: instance.DefinitionId,
+		BusinessKey:  instance.BusinessKey,
+		Ended:        instance.State != ProcessInstanceActive,
+		Links:        e.processInstanceLinks(instance),
+	}
+	if req.WithVariablesInReturn != nil && *req.WithVariablesInReturn {
+		res.Variables = copyVariables(instance.Variables)
+	}
+
+	return res, nil
+}
+
+func (p *ProcessDefinition) response() *camundaclientgo.ResProcessDefinition {
+	return &camundaclientgo.ResProcessDefinition{
+		Id:                  p.Id,
+		Key:                 p.Key,
+		Name:                p.Name,
+		Version:             p.Version,
+		Resource:            p.Resource,
+		DeploymentId:        p.DeploymentId,
+		StartableInTasklist: true,
+	}
+}
+
+// processInstanceFilter parameters of process instance queries
+type processInstanceFilter struct {
+	ProcessInstanceIds   []string `json:"processInstanceIds"`
+	BusinessKey          string   `json:"businessKey"`
+	ProcessDefinitionId  string   `json:"processDefinitionId"`
+	ProcessDefinitionKey string   `json:"processDefinitionKey"`
+	DeploymentId         string   `json:"deploymentId"`
+	ActivityIdIn         []string `json:"activityIdIn"`
+}
+
+func queryProcessInstanceFilter(query url.Values) *processInstanceFilter {
+	return &processInstanceFilter{
+		ProcessInstanceIds:   splitList(query.Get("processInstanceIds")),
+		BusinessKey:          query.Get("businessKey"),
+		ProcessDefinitionId:  query.Get("processDefinitionId"),
+		ProcessDefinitionKey: query.Get("processDefinitionKey"),
+		DeploymentId:         query.Get("deploymentId"),
+		ActivityIdIn:         splitList(query.Get("activityIdIn")),
+	}
+}
+
+func (f *processInstanceFilter) match(instance *ProcessInstance) bool {
+	if len(f.ActivityIdIn) > 0 {
+		found := false
+		for _, activityId := range instance.ActivityIds {
+			found = found || contains(f.ActivityIdIn, activityId)
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return (len(f.ProcessInstanceIds) == 0 || contains(f.ProcessInstanceIds, instance.Id)) &&
+		(f.BusinessKey == "" || instance.BusinessKey == f.BusinessKey) &&
+		(f.ProcessDefinitionId == "" || instance.DefinitionId == f.ProcessDefinitionId) &&
+		(f.ProcessDefinitionKey == "" || instance.DefinitionKey == f.ProcessDefinitionKey) &&
+		(f.DeploymentId == "" || instance.definition.DeploymentId == f.DeploymentId)
+}
+
+// findProcessInstances returns active process instances matching the filter
+func (e *Engine) findProcessInstances(filter *processInstanceFilter) []*ProcessInstance {
+	var result []*ProcessInstance
+	for _, instance := range e.instances {
+		if instance.State == ProcessInstanceActive && filter.match(instance) {
+			result = append(result, instance)
+		}
+	}
+
+	return result
+}
+
+func (e *Engine) processInstancesResponse(r *http.Request, instances []*ProcessInstance) []*camundaclientgo.ResProcessInstance {
+	first, last := page(r, len(instances))
+
+	res := []*camundaclientgo.ResProcessInstance{}
+	for _, instance := range instances[first:last] {
+		res = append(res, e.processInstanceResponse(instance))
+	}
+
+	return res
+}
+
+func getProcessInstances(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return e.processInstancesResponse(r, e.findProcessInstances(queryProcessInstanceFilter(r.URL.Query()))), nil
+}
+
+func getProcessInstancesPost(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	filter := &processInstanceFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	return e.processInstancesResponse(r, e.findProcessInstances(filter)), nil
+}
+
+func getProcessInstancesCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	return &camundaclientgo.ResCount{Count: len(e.findProcessInstances(queryProcessInstanceFilter(r.URL.Query())))}, nil
+}
+
+func getProcessInstancesCountPost(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	filter := &processInstanceFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	return &camundaclientgo.ResCount{Count: len(e.findProcessInstances(filter))}, nil
+}
+
+func (e *Engine) getActiveInstance(id string) (*ProcessInstance, error) {
+	instance := e.activeInstance(id)
+	if instance == nil {
+		return nil, notFound("Process instance with id %s does not exist", id)
+	}
+
+	return instance, nil
+}
+
+func getProcessInstance(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return e.processInstanceResponse(instance), nil
+}
+
+func deleteProcessInstance(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	e.endInstance(instance, ProcessInstanceTerminated)
+	return nil, nil
+}
+
+func getActivityInstances(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	res := &camundaclientgo.ResProcessActivityInstance{
+		Id:                       instance.Id,
+		ActivityId:               instance.DefinitionId,
+		ActivityType:             "processDefinition",
+		ProcessInstanceId:        instance.Id,
+		ProcessDefinitionId:      instance.DefinitionId,
+		ChildActivityInstances:   []camundaclientgo.ResProcessActivityInstance{},
+		ChildTransitionInstances: []camundaclientgo.ResProcessTransitionInstance{},
+		ExecutionIds:             []string{instance.Id},
+	}
+	for i, activityId := range instance.ActivityIds {
+		element := instance.definition.process.elements[activityId]
+		res.ChildActivityInstances = append(res.ChildActivityInstances, camundaclientgo.ResProcessActivityInstance{
+			Id:                       activityId + ":" + instance.Id + ":" + strconv.Itoa(i),
+			ParentActivityInstanceId: instance.Id,
+			ActivityId:               activityId,
+			ActivityName:             element.Name,
+			ActivityType:             element.kind(),
+			ProcessInstanceId:        instance.Id,
+			ProcessDefinitionId:      instance.DefinitionId,
+			ChildActivityInstances:   []camundaclientgo.ResProcessActivityInstance{},
+			ChildTransitionInstances: []camundaclientgo.ResProcessTransitionInstance{},
+			ExecutionIds:             []string{instance.Id},
+		})
+	}
+
+	return res, nil
+}
+
+func getProcessVariables(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return copyVariables(instance.Variables), nil
+}
+
+func modifyProcessVariables(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := struct {
+		Modifications map[string]camundaclientgo.Variable `json:"modifications"`
+		Deletions     []string                            `json:"deletions"`
+	}{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	for name, variable := range req.Modifications {
+		instance.Variables[name] = variable
+	}
+	for _, name := range req.Deletions {
+		delete(instance.Variables, name)
+	}
+
+	return nil, nil
+}
+
+func getProcessVariable(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	variable, ok := instance.Variables[params[1]]
+	if !ok {
+		return nil, notFound("process instance variable with name %s does not exist", params[1])
+	}
+
+	return &variable, nil
+}
+
+func putProcessVariable(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	variable := camundaclientgo.Variable{}
+	if err := readJson(r, &variable); err != nil {
+		return nil, err
+	}
+	instance.Variables[params[1]] = variable
+
+	return nil, nil
+}
+
+func deleteProcessVariable(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	instance, err := e.getActiveInstance(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	delete(instance.Variables, params[1])
+	return nil, nil
+}
+
+func (e *Engine) processInstanceLinks(instance *ProcessInstance) []camundaclientgo.ResLink {
+	return []camundaclientgo.ResLink{
+		{Method: http.MethodGet, Href: e.URL() + "/process-instance/" + instance.Id, Rel: "self"},
+	}
+}
+
+func (e *Engine) processInstanceResponse(instance *ProcessInstance) *camundaclientgo.ResProcessInstance {
+	return &camundaclientgo.ResProcessInstance{
+		Id:           instance.Id,
+		DefinitionId: instance.DefinitionId,
+		BusinessKey:  instance.BusinessKey,
+		Links:        e.processInstanceLinks(instance),
+	}
+}
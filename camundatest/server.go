@@ -0,0 +1,277 @@
+package camundatest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// handlerFunc handles a request with the engine lock held, params are values of `*` segments of the route.
+// A nil result is written as 204 No Content
+type handlerFunc func(e *Engine, r *http.Request, params []string) (interface{}, error)
+
+type route struct {
+	method   string
+	segments []string
+	handler  handlerFunc
+}
+
+// rawResponse a response which is not JSON
+type rawResponse struct {
+	contentType string
+	data        []byte
+}
+
+// engineError an error response of the engine
+type engineError struct {
+	status  int
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *engineError) Error() string {
+	return e.Message
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &engineError{status: http.StatusNotFound, Type: "InvalidRequestException", Message: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &engineError{status: http.StatusBadRequest, Type: "InvalidRequestException", Message: fmt.Sprintf(format, args...)}
+}
+
+func badUserRequest(format string, args ...interface{}) error {
+	return &engineError{status: http.StatusBadRequest, Type: "BadUserRequestException", Message: fmt.Sprintf(format, args...)}
+}
+
+func engineFailure(err error) *engineError {
+	return &engineError{status: http.StatusInternalServerError, Type: "ProcessEngineException", Message: err.Error()}
+}
+
+var routes []route
+
+func handle(method, pattern string, handler handlerFunc) {
+	routes = append(routes, route{
+		method:   method,
+		segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+		handler:  handler,
+	})
+}
+
+func init() {
+	handle(http.MethodGet, "/version", getVersion)
+
+	handle(http.MethodPost, "/deployment/create", createDeployment)
+	handle(http.MethodGet, "/deployment", getDeployments)
+	handle(http.MethodGet, "/deployment/count", getDeploymentsCount)
+	handle(http.MethodGet, "/deployment/*", getDeployment)
+	handle(http.MethodDelete, "/deployment/*", deleteDeployment)
+	handle(http.MethodGet, "/deployment/*/resources", getDeploymentResources)
+	handle(http.MethodGet, "/deployment/*/resources/*", getDeploymentResource)
+	handle(http.MethodGet, "/deployment/*/resources/*/data", getDeploymentResourceData)
+
+	handle(http.MethodGet, "/process-definition", getProcessDefinitions)
+	handle(http.MethodGet, "/process-definition/count", getProcessDefinitionsCount)
+	handle(http.MethodGet, "/process-definition/key/*", getProcessDefinitionByKey)
+	handle(http.MethodGet, "/process-definition/key/*/xml", getProcessDefinitionXmlByKey)
+	handle(http.MethodPost, "/process-definition/key/*/start", startProcessInstanceByKey)
+	handle(http.MethodGet, "/process-definition/*", getProcessDefinition)
+	handle(http.MethodGet, "/process-definition/*/xml", getProcessDefinitionXml)
+	handle(http.MethodPost, "/process-definition/*/start", startProcessInstance)
+
+	handle(http.MethodGet, "/process-instance", getProcessInstances)
+	handle(http.MethodPost, "/process-instance", getProcessInstancesPost)
+	handle(http.MethodGet, "/process-instance/count", getProcessInstancesCount)
+	handle(http.MethodPost, "/process-instance/count", getProcessInstancesCountPost)
+	handle(http.MethodGet, "/process-instance/*", getProcessInstance)
+	handle(http.MethodDelete, "/process-instance/*", deleteProcessInstance)
+	handle(http.MethodGet, "/process-instance/*/activity-instances", getActivityInstances)
+	handle(http.MethodGet, "/process-instance/*/variables", getProcessVariables)
+	handle(http.MethodPost, "/process-instance/*/variables", modifyProcessVariables)
+	handle(http.MethodGet, "/process-instance/*/variables/*", getProcessVariable)
+	handle(http.MethodPut, "/process-instance/*/variables/*", putProcessVariable)
+	handle(http.MethodPost, "/process-instance/*/variables/*", putProcessVariable)
+	handle(http.MethodDelete, "/process-instance/*/variables/*", deleteProcessVariable)
+
+	handle(http.MethodPost, "/external-task/fetchAndLock", fetchAndLock)
+	handle(http.MethodGet, "/external-task", getExternalTasks)
+	handle(http.MethodPost, "/external-task", getExternalTasksPost)
+	handle(http.MethodGet, "/external-task/count", getExternalTasksCount)
+	handle(http.MethodPost, "/external-task/count", getExternalTasksCountPost)
+	handle(http.MethodGet, "/external-task/*", getExternalTask)
+	handle(http.MethodPost, "/external-task/*/complete", completeExternalTask)
+	handle(http.MethodPost, "/external-task/*/failure", handleExternalTaskFailure)
+	handle(http.MethodPost, "/external-task/*/bpmnError", handleExternalTaskBPMNError)
+	handle(http.MethodPost, "/external-task/*/bpmnEscalation", handleExternalTaskEscalation)
+	handle(http.MethodPost, "/external-task/*/extendLock", extendExternalTaskLock)
+	handle(http.MethodPost, "/external-task/*/unlock", unlockExternalTask)
+	handle(http.MethodPut, "/external-task/*/retries", setExternalTaskRetries)
+	handle(http.MethodPut, "/external-task/*/priority", setExternalTaskPriority)
+
+	handle(http.MethodPost, "/task", getUserTasks)
+	handle(http.MethodPost, "/task/count", getUserTasksCount)
+	handle(http.MethodGet, "/task/*", getUserTask)
+	handle(http.MethodPost, "/task/*/complete", completeUserTask)
+	handle(http.MethodPost, "/task/*/assignee", setUserTaskAssignee)
+
+	handle(http.MethodPost, "/message", correlateMessage)
+
+	handle(http.MethodGet, "/history/process-instance", getHistoryProcessInstances)
+	handle(http.MethodGet, "/history/process-instance/count", getHistoryProcessInstancesCount)
+	handle(http.MethodGet, "/history/process-instance/*", getHistoryProcessInstance)
+	handle(http.MethodGet, "/history/variable-instance", getHistoryVariableInstances)
+	handle(http.MethodGet, "/history/variable-instance/count", getHistoryVariableInstancesCount)
+	handle(http.MethodGet, "/history/variable-instance/*", getHistoryVariableInstance)
+	handle(http.MethodPost, "/history/task", getHistoryUserTasks)
+	handle(http.MethodPost, "/history/task/count", getHistoryUserTasksCount)
+}
+
+// match returns values of `*` segments if the path matches the route
+func (rt *route) match(method string, segments []string) ([]string, bool) {
+	if rt.method != method || len(rt.segments) != len(segments) {
+		return nil, false
+	}
+
+	var params []string
+	for i, segment := range rt.segments {
+		if segment == "*" {
+			params = append(params, segments[i])
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// ServeHTTP serves the REST API of the engine under `/engine-rest`
+func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/engine-rest")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i := range routes {
+		params, ok := routes[i].match(r.Method, segments)
+		if !ok {
+			continue
+		}
+
+		e.mu.Lock()
+		result, err := routes[i].handler(e, r, params)
+		if err == nil && r.Method != http.MethodGet {
+			e.notify()
+		}
+		e.mu.Unlock()
+
+		writeResponse(w, result, err)
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("camundatest: %s %s is not implemented by the fake engine", r.Method, path), http.StatusNotImplemented)
+}
+
+func writeResponse(w http.ResponseWriter, result interface{}, err error) {
+	if err != nil {
+		var engineErr *engineError
+		if !errors.As(err, &engineErr) {
+			engineErr = engineFailure(err)
+		}
+
+		writeJson(w, engineErr.status, engineErr)
+		return
+	}
+
+	switch v := result.(type) {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case *rawResponse:
+		w.Header().Set("Content-Type", v.contentType)
+		_, _ = w.Write(v.data)
+	default:
+		writeJson(w, http.StatusOK, v)
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
+
+// readJson decodes the request body, an empty body is allowed
+func readJson(r *http.Request, v interface{}) error {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return badRequest("failed read request body: %s", err)
+	}
+
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest("failed parse request body: %s", err)
+	}
+
+	return nil
+}
+
+func getVersion(*Engine, *http.Request, []string) (interface{}, error) {
+	return map[string]string{"version": EngineVersion}, nil
+}
+
+// page returns bounds of the result page by the firstResult and maxResults query parameters
+func page(r *http.Request, total int) (int, int) {
+	first, _ := strconv.Atoi(r.URL.Query().Get("firstResult"))
+	if first < 0 || first > total {
+		first = total
+	}
+
+	last := total
+	if max, err := strconv.Atoi(r.URL.Query().Get("maxResults")); err == nil && max >= 0 && first+max < total {
+		last = first + max
+	}
+
+	return first, last
+}
+
+// splitList splits a comma separated list of a query parameter
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, ",")
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatTime formats the time for a response, zero time is empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(camundaclientgo.DefaultDateTimeFormat)
+}
@@ -0,0 +1,138 @@
+package camundatest
+
+import (
+	"net/http"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// userTaskFilter parameters of user task queries
+type userTaskFilter struct {
+	ProcessInstanceId          string   `json:"processInstanceId"`
+	ProcessInstanceIdIn        []string `json:"processInstanceIdIn"`
+	ProcessInstanceBusinessKey string   `json:"processInstanceBusinessKey"`
+	ProcessDefinitionId        string   `json:"processDefinitionId"`
+	ProcessDefinitionKey       string   `json:"processDefinitionKey"`
+	TaskDefinitionKey          string   `json:"taskDefinitionKey"`
+	Name                       string   `json:"name"`
+	Assignee                   string   `json:"assignee"`
+	Unassigned                 bool     `json:"unassigned"`
+}
+
+func (f *userTaskFilter) match(task *UserTask) bool {
+	return (f.ProcessInstanceId == "" || task.ProcessInstanceId == f.ProcessInstanceId) &&
+		(len(f.ProcessInstanceIdIn) == 0 || contains(f.ProcessInstanceIdIn, task.ProcessInstanceId)) &&
+		(f.ProcessInstanceBusinessKey == "" || task.BusinessKey == f.ProcessInstanceBusinessKey) &&
+		(f.ProcessDefinitionId == "" || task.ProcessDefinitionId == f.ProcessDefinitionId) &&
+		(f.ProcessDefinitionKey == "" || task.ProcessDefinitionKey == f.ProcessDefinitionKey) &&
+		(f.TaskDefinitionKey == "" || task.TaskDefinitionKey == f.TaskDefinitionKey) &&
+		(f.Name == "" || task.Name == f.Name) &&
+		(f.Assignee == "" || task.Assignee == f.Assignee) &&
+		(!f.Unassigned || task.Assignee == "")
+}
+
+// findUserTasks returns user tasks matching the filter of the request body, only active tasks unless history is true
+func (e *Engine) findUserTasks(r *http.Request, history bool) ([]*UserTask, error) {
+	filter := &userTaskFilter{}
+	if err := readJson(r, filter); err != nil {
+		return nil, err
+	}
+
+	var result []*UserTask
+	for _, task := range e.userTasks {
+		if (history || task.State == UserTaskActive) && filter.match(task) {
+			result = append(result, task)
+		}
+	}
+
+	return result, nil
+}
+
+func getUserTasks(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	tasks, err := e.findUserTasks(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := page(r, len(tasks))
+	res := []*camundaclientgo.UserTaskResponse{}
+	for _, task := range tasks[first:last] {
+		res = append(res, task.response())
+	}
+
+	return res, nil
+}
+
+func getUserTasksCount(e *Engine, r *http.Request, _ []string) (interface{}, error) {
+	tasks, err := e.findUserTasks(r, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &camundaclientgo.ResCount{Count: len(tasks)}, nil
+}
+
+func (e *Engine) getUserTask(id string) (*UserTask, error) {
+	task := e.userTask(id)
+	if task == nil || task.State != UserTaskActive {
+		return nil, notFound("No matching task with id %s", id)
+	}
+
+	return task, nil
+}
+
+func getUserTask(e *Engine, _ *http.Request, params []string) (interface{}, error) {
+	task, err := e.getUserTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return task.response(), nil
+}
+
+func completeUserTask(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	task, err := e.getUserTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := camundaclientgo.QueryUserTaskComplete{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+
+	if err := e.completeUserTask(task, req.Variables); err != nil {
+		return nil, engineFailure(err)
+	}
+
+	return nil, nil
+}
+
+func setUserTaskAssignee(e *Engine, r *http.Request, params []string) (interface{}, error) {
+	task, err := e.getUserTask(params[0])
+	if err != nil {
+		return nil, err
+	}
+
+	req := camundaclientgo.Assignee{}
+	if err := readJson(r, &req); err != nil {
+		return nil, err
+	}
+	task.Assignee = req.UserId
+
+	return nil, nil
+}
+
+func (t *UserTask) response() *camundaclientgo.UserTaskResponse {
+	return &camundaclientgo.UserTaskResponse{
+		Id:                  t.Id,
+		Name:                t.Name,
+		Assignee:            t.Assignee,
+		Created:             formatTime(t.Created),
+		ExecutionId:         t.ProcessInstanceId,
+		ProcessDefinitionId: t.ProcessDefinitionId,
+		ProcessInstanceId:   t.ProcessInstanceId,
+		TaskDefinitionKey:   t.TaskDefinitionKey,
+		Priority:            50,
+	}
+}
@@ -0,0 +1,166 @@
+package camunda_client_go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitBreakerFailureThreshold = 5
+	DefaultCircuitBreakerOpenTimeout      = 30 * time.Second
+)
+
+// ErrorCircuitOpen matches errors returned by the client while the circuit breaker is open, use errors.Is
+var ErrorCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError an error returned without a request to the engine while the circuit breaker is open
+type CircuitOpenError struct {
+	// the time when the circuit breaker lets a probe request through
+	RetryAt time.Time
+}
+
+// Error error message
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrorCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrorCircuitOpen
+}
+
+// CircuitState a state of the circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed requests are sent to the engine
+	CircuitClosed CircuitState = iota
+	// CircuitOpen requests fail fast with CircuitOpenError
+	CircuitOpen
+	// CircuitHalfOpen one probe request is sent to the engine, others fail fast
+	CircuitHalfOpen
+)
+
+// String returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreakerOptions options for NewCircuitBreaker
+type CircuitBreakerOptions struct {
+	// consecutive failed requests which open the circuit breaker (default: 5)
+	FailureThreshold int
+	// time in the open state before a probe request is sent (default: 30 seconds)
+	OpenTimeout time.Duration
+}
+
+// CircuitBreaker stops requests to the engine after consecutive failures.
+// Failures are connection errors and 5xx responses which are not engine errors, see ClientOptions.CircuitBreaker
+type CircuitBreaker struct {
+	options CircuitBreakerOptions
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker a create new instance CircuitBreaker
+func NewCircuitBreaker(options CircuitBreakerOptions) *CircuitBreaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = DefaultCircuitBreakerFailureThreshold
+	}
+	if options.OpenTimeout <= 0 {
+		options.OpenTimeout = DefaultCircuitBreakerOpenTimeout
+	}
+
+	return &CircuitBreaker{options: options}
+}
+
+// State returns the current state of the circuit breaker
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.options.OpenTimeout {
+		return CircuitHalfOpen
+	}
+
+	return b.state
+}
+
+// Interceptor returns an interceptor which fails fast while the circuit breaker is open
+func (b *CircuitBreaker) Interceptor() Interceptor {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *Request) (*http.Response, error) {
+			if err := b.allow(); err != nil {
+				return nil, err
+			}
+
+			res, err := next(req)
+			if err != nil && req.Context.Err() != nil {
+				b.abort()
+			} else {
+				b.done(isEndpointFailure(req.Context, res, err))
+			}
+			return res, err
+		}
+	}
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		retryAt := b.openedAt.Add(b.options.OpenTimeout)
+		if time.Now().Before(retryAt) {
+			return &CircuitOpenError{RetryAt: retryAt}
+		}
+		b.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// the probe request is in progress
+		return &CircuitOpenError{RetryAt: time.Now().Add(b.options.OpenTimeout)}
+	default:
+		return nil
+	}
+}
+
+// abort returns the circuit breaker to the open state if the probe request is cancelled
+func (b *CircuitBreaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitHalfOpen {
+		b.state = CircuitOpen
+	}
+}
+
+func (b *CircuitBreaker) done(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.options.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,59 @@
+package camunda_client_go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var available int32
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&available) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(CircuitBreakerOptions{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+	})
+	client := NewClient(ClientOptions{
+		EndpointUrl:  server.URL,
+		Interceptors: []Interceptor{breaker.Interceptor()},
+	})
+
+	assert.Error(t, client.ExternalTask.Unlock("task-id"))
+	assert.Equal(t, CircuitClosed, breaker.State())
+	assert.Error(t, client.ExternalTask.Unlock("task-id"))
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	err := client.ExternalTask.Unlock("task-id")
+	assert.True(t, errors.Is(err, ErrorCircuitOpen))
+	var openErr *CircuitOpenError
+	assert.True(t, errors.As(err, &openErr))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	// the failed probe opens the circuit breaker again
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, breaker.State())
+	assert.Error(t, client.ExternalTask.Unlock("task-id"))
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	// the successful probe closes the circuit breaker
+	atomic.StoreInt32(&available, 1)
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+	assert.Equal(t, CircuitClosed, breaker.State())
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
@@ -2,7 +2,9 @@ package camunda_client_go
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,18 +24,55 @@ const DefaultDateTimeFormat = "2006-01-02T15:04:05.000-0700"
 type ClientOptions struct {
 	UserAgent   string
 	EndpointUrl string
-	Timeout     time.Duration
-	ApiUser     string
-	ApiPassword string
+	// EndpointUrls urls of several engine nodes sharing one database, EndpointUrl is used together with them.
+	// A failed node is ejected and idempotent requests are sent to another node
+	EndpointUrls []string
+	// EndpointSelection a strategy of selecting a node for a request (default: EndpointRoundRobin)
+	EndpointSelection EndpointSelection
+	// ProbeInterval an interval between probes of an ejected node by `/version` (default: 10 seconds)
+	ProbeInterval time.Duration
+	Timeout       time.Duration
+	ApiUser       string
+	ApiPassword   string
+	// Authenticator sets credentials of requests (default: basic authentication by ApiUser and ApiPassword,
+	// no authentication if both are empty)
+	Authenticator Authenticator
+	// Interceptors wrap every request to the engine, the first interceptor is the outermost one
+	Interceptors []Interceptor
+	// Tracer creates a span for every request to the engine
+	Tracer Tracer
+	// Logger logs every request at the debug level and failed requests at the warn level
+	Logger Logger
+	// LogBodies logs request and response bodies with redacted secrets, see NewRequestLogInterceptor
+	LogBodies bool
+	// TLS configuration of connections to the engine, e.g. CA certificates and the client certificate for mTLS
+	TLS *TLSOptions
+	// Proxy returns the proxy url for a request (default: http.ProxyFromEnvironment)
+	Proxy func(*http.Request) (*url.URL, error)
+	// maximum idle connections to the engine (default: 100)
+	MaxIdleConns int
+	// maximum idle connections per engine host (default: 10)
+	MaxIdleConnsPerHost int
+	// time after which an idle connection is closed (default: 90 seconds)
+	IdleConnTimeout time.Duration
+	// RateLimit limits all requests to the engine, requests exceeding the limit wait (default: unlimited)
+	RateLimit *RateLimit
+	// OperationRateLimits limit requests per operation, e.g. `ProcessDefinition.StartInstance`
+	OperationRateLimits map[string]RateLimit
+	// CircuitBreaker fails requests fast with CircuitOpenError after consecutive failures of the engine.
+	// Use NewCircuitBreaker and Interceptors to read the state of the circuit breaker
+	CircuitBreaker *CircuitBreakerOptions
 }
 
 // Client a client for Camunda API
 type Client struct {
-	httpClient  *http.Client
-	endpointUrl string
-	userAgent   string
-	apiUser     string
-	apiPassword string
+	httpClient    *http.Client
+	endpoints     *endpointPool
+	userAgent     string
+	authenticator Authenticator
+	roundTrip     RoundTripFunc
+	tracer        Tracer
+	ctx           context.Context
 
 	ExternalTask      *ExternalTask
 	Deployment        *Deployment
@@ -54,6 +93,8 @@ var ErrorNotFound = &Error{
 type Error struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	// StatusCode the http status code of the response, 0 for ErrorNotFound
+	StatusCode int `json:"-"`
 }
 
 // Error error message
@@ -61,6 +102,21 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// ResponseError an error response without a JSON body, e.g. of a proxy in front of the engine
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error error message
+func (e *ResponseError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("response error with status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("response error with status code %d: %s", e.StatusCode, e.Body)
+}
+
 // Time a custom time format
 type Time struct {
 	time.Time
@@ -92,16 +148,35 @@ func NewClient(options ClientOptions) *Client {
 	client := &Client{
 		httpClient: &http.Client{
 			Timeout: time.Second * DefaultTimeoutSec,
+			Transport: newTransport(transportOptions{
+				tls:                 options.TLS,
+				proxy:               options.Proxy,
+				maxIdleConns:        options.MaxIdleConns,
+				maxIdleConnsPerHost: options.MaxIdleConnsPerHost,
+				idleConnTimeout:     options.IdleConnTimeout,
+			}),
 		},
-		endpointUrl: DefaultEndpointUrl,
-		userAgent:   DefaultUserAgent,
-		apiUser:     options.ApiUser,
-		apiPassword: options.ApiPassword,
+		userAgent:     DefaultUserAgent,
+		authenticator: NoAuthenticator{},
+		tracer:        NoopTracer{},
+		ctx:           context.Background(),
 	}
 
+	if options.Authenticator != nil {
+		client.authenticator = options.Authenticator
+	} else if options.ApiUser != "" || options.ApiPassword != "" {
+		client.authenticator = &BasicAuthenticator{User: options.ApiUser, Password: options.ApiPassword}
+	}
+
+	var endpointUrls []string
 	if options.EndpointUrl != "" {
-		client.endpointUrl = options.EndpointUrl
+		endpointUrls = append(endpointUrls, options.EndpointUrl)
+	}
+	endpointUrls = append(endpointUrls, options.EndpointUrls...)
+	if len(endpointUrls) == 0 {
+		endpointUrls = []string{DefaultEndpointUrl}
 	}
+	client.endpoints = newEndpointPool(endpointUrls, options.EndpointSelection, options.ProbeInterval)
 
 	if options.UserAgent != "" {
 		client.userAgent = options.UserAgent
@@ -111,18 +186,58 @@ func NewClient(options ClientOptions) *Client {
 		client.httpClient.Timeout = options.Timeout
 	}
 
-	client.ExternalTask = &ExternalTask{client: client}
-	client.Deployment = &Deployment{client: client}
-	client.ProcessDefinition = &ProcessDefinition{client: client}
-	client.ProcessInstance = &ProcessInstance{client: client}
-	client.UserTask = &userTaskApi{client: client}
-	client.Message = &Message{client: client}
-	client.History = &History{client: client}
-	client.Tenant = &Tenant{client: client}
+	if options.Tracer != nil {
+		client.tracer = options.Tracer
+	}
+
+	interceptors := append([]Interceptor{}, options.Interceptors...)
+	if options.RateLimit != nil || len(options.OperationRateLimits) > 0 {
+		interceptors = append(interceptors, NewRateLimitInterceptor(RateLimitOptions{
+			Global:     options.RateLimit,
+			Operations: options.OperationRateLimits,
+		}))
+	}
+	if options.CircuitBreaker != nil {
+		interceptors = append(interceptors, NewCircuitBreaker(*options.CircuitBreaker).Interceptor())
+	}
+	if options.Logger != nil {
+		interceptors = append(interceptors, NewRequestLogInterceptor(options.Logger, RequestLogOptions{
+			LogBodies: options.LogBodies,
+		}))
+	}
+
+	client.roundTrip = chainInterceptors(client.send, interceptors)
+	client.bindApi()
 
 	return client
 }
 
+// WithContext returns a shallow copy of the client which sends all requests with ctx.
+// The context carries cancellation, deadlines and the parent span of requests
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := *c
+	client.ctx = ctx
+	client.bindApi()
+
+	return &client
+}
+
+// Tracer returns the tracer of the client
+func (c *Client) Tracer() Tracer {
+	return c.tracer
+}
+
+func (c *Client) bindApi() {
+	c.ExternalTask = &ExternalTask{client: c}
+	c.Deployment = &Deployment{client: c}
+	c.ProcessDefinition = &ProcessDefinition{client: c}
+	c.ProcessInstance = &ProcessInstance{client: c}
+	c.UserTask = &userTaskApi{client: c}
+	c.Message = &Message{client: c}
+	c.History = &History{client: c}
+	c.Tenant = &Tenant{client: c}
+}
+
 // SetCustomTransport set new custom transport
 func (c *Client) SetCustomTransport(customHTTPTransport http.RoundTripper) {
 	if c.httpClient != nil {
@@ -166,31 +281,115 @@ func (c *Client) doPost(path string, query map[string]string) (res *http.Respons
 }
 
 func (c *Client) do(method, path string, query map[string]string, body io.Reader, contentType string) (res *http.Response, err error) {
-	url, err := c.buildUrl(path, query)
+	operation := operationName()
+	ctx, span := c.tracer.Start(c.ctx, operation, SpanKindClient)
+	defer span.End()
+	span.SetAttributes(
+		Attribute{Key: AttributeOperation, Value: operation},
+		Attribute{Key: AttributeHttpMethod, Value: method},
+		Attribute{Key: AttributeEnginePath, Value: path},
+	)
+
+	req := &Request{
+		Context:    ctx,
+		Operation:  operation,
+		Method:     method,
+		Path:       path,
+		Query:      query,
+		Header:     http.Header{},
+		Idempotent: isIdempotent(method, operation),
+	}
+	if body != nil {
+		if req.Body, err = ioutil.ReadAll(body); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err = c.roundTrip(req)
 	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(Attribute{Key: AttributeHttpStatusCode, Value: res.StatusCode})
+	if err := c.checkResponse(res); err != nil {
+		var camundaErr *Error
+		if errors.As(err, &camundaErr) {
+			span.SetAttributes(Attribute{Key: AttributeErrorType, Value: camundaErr.Type})
+		}
+		span.RecordError(err)
 		return nil, err
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	return
+}
+
+// send executes the request by http client, it is the innermost RoundTripFunc of the interceptor chain.
+// An idempotent request is sent to the next node if the selected node fails
+func (c *Client) send(req *Request) (*http.Response, error) {
+	c.probeEndpoints()
+
+	tried := map[*endpoint]bool{}
+	for {
+		e := c.endpoints.pick(tried)
+		tried[e] = true
+
+		res, err := c.sendTo(e, req)
+		failed := isEndpointFailure(req.Context, res, err)
+		c.endpoints.report(e, failed)
+		if !failed || !req.Idempotent || len(tried) == len(c.endpoints.endpoints) {
+			return res, err
+		}
+
+		if res != nil {
+			res.Body.Close()
+		}
+	}
+}
+
+// sendTo executes the request on the node. If the engine responds 401 Unauthorized
+// and the credentials are refreshable, the request is retried once
+func (c *Client) sendTo(e *endpoint, req *Request) (*http.Response, error) {
+	res, err := c.sendOnce(e, req)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", c.userAgent)
-	if contentType != "" {
-		req.Header.Set("Content-Type", contentType)
+
+	if auth, ok := c.authenticator.(RefreshableAuthenticator); ok && res.StatusCode == http.StatusUnauthorized {
+		res.Body.Close()
+		auth.Invalidate()
+		return c.sendOnce(e, req)
 	}
 
-	req.SetBasicAuth(c.apiUser, c.apiPassword)
+	return res, nil
+}
 
-	res, err = c.httpClient.Do(req)
+func (c *Client) sendOnce(e *endpoint, req *Request) (*http.Response, error) {
+	url, err := buildUrl(e.url, req.Path, req.Query)
 	if err != nil {
 		return nil, err
 	}
-	if err := c.checkResponse(res); err != nil {
+
+	var body io.Reader
+	if req.Body != nil {
+		body = bytes.NewReader(req.Body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(req.Context, req.Method, url, body)
+	if err != nil {
 		return nil, err
 	}
+	httpReq.Header = req.Header.Clone()
 
-	return
+	if err := c.authenticator.Authenticate(httpReq); err != nil {
+		return nil, fmt.Errorf("failed authenticate request: %w", err)
+	}
+
+	return c.httpClient.Do(httpReq)
 }
 
 func (c *Client) doGet(path string, query map[string]string) (res *http.Response, err error) {
@@ -209,7 +408,7 @@ func (c *Client) checkResponse(res *http.Response) error {
 			return ErrorNotFound
 		}
 
-		jsonErr := &Error{}
+		jsonErr := &Error{StatusCode: res.StatusCode}
 		err := json.NewDecoder(res.Body).Decode(jsonErr)
 		if err != nil {
 			return fmt.Errorf("response error with status code %d: failed unmarshal error response: %w", res.StatusCode, err)
@@ -220,10 +419,10 @@ func (c *Client) checkResponse(res *http.Response) error {
 
 	errText, err := ioutil.ReadAll(res.Body)
 	if err == nil {
-		return fmt.Errorf("response error with status code %d: %s", res.StatusCode, string(errText))
+		return &ResponseError{StatusCode: res.StatusCode, Body: string(errText)}
 	}
 
-	return fmt.Errorf("response error with status code %d", res.StatusCode)
+	return &ResponseError{StatusCode: res.StatusCode}
 }
 
 func (c *Client) readJsonResponse(res *http.Response, v interface{}) error {
@@ -236,11 +435,11 @@ func (c *Client) readJsonResponse(res *http.Response, v interface{}) error {
 	return nil
 }
 
-func (c *Client) buildUrl(path string, query map[string]string) (string, error) {
+func buildUrl(endpointUrl, path string, query map[string]string) (string, error) {
 	if len(query) == 0 {
-		return c.endpointUrl + path, nil
+		return endpointUrl + path, nil
 	}
-	url, err := url.Parse(c.endpointUrl + path)
+	url, err := url.Parse(endpointUrl + path)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,210 @@
+package camunda_client_go
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultProbeInterval a default interval between probes of an ejected endpoint
+const DefaultProbeInterval = 10 * time.Second
+
+// EndpointSelection a strategy of selecting an engine endpoint for a request
+type EndpointSelection int
+
+const (
+	// EndpointRoundRobin selects healthy endpoints in turn
+	EndpointRoundRobin EndpointSelection = iota
+	// EndpointLeastErrors selects the healthy endpoint with the fewest recent errors
+	EndpointLeastErrors
+)
+
+// endpoint an engine node
+type endpoint struct {
+	url string
+
+	// guarded by endpointPool.mu
+	healthy   bool
+	errors    int
+	ejectedAt time.Time
+	probing   bool
+}
+
+// endpointPool selects endpoints and tracks their health passively:
+// an endpoint is ejected on connection errors and 5xx responses of proxies,
+// and returns to the pool after a successful probe of `/version`
+type endpointPool struct {
+	selection     EndpointSelection
+	probeInterval time.Duration
+	endpoints     []*endpoint
+
+	mu   sync.Mutex
+	next int
+}
+
+func newEndpointPool(urls []string, selection EndpointSelection, probeInterval time.Duration) *endpointPool {
+	if probeInterval <= 0 {
+		probeInterval = DefaultProbeInterval
+	}
+
+	pool := &endpointPool{
+		selection:     selection,
+		probeInterval: probeInterval,
+	}
+	for _, url := range urls {
+		pool.endpoints = append(pool.endpoints, &endpoint{
+			url:     strings.TrimSuffix(url, "/"),
+			healthy: true,
+		})
+	}
+
+	return pool
+}
+
+// pick returns an endpoint which is not in tried, healthy endpoints are preferred.
+// If all endpoints are ejected, the longest ejected one is returned. It returns nil if all endpoints are tried
+func (p *endpointPool) pick(tried map[*endpoint]bool) *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var picked, fallback *endpoint
+	for i := 0; i < len(p.endpoints); i++ {
+		e := p.endpoints[(p.next+i)%len(p.endpoints)]
+		if tried[e] {
+			continue
+		}
+
+		if !e.healthy {
+			if fallback == nil || e.ejectedAt.Before(fallback.ejectedAt) {
+				fallback = e
+			}
+			continue
+		}
+
+		if picked == nil {
+			picked = e
+			if p.selection == EndpointRoundRobin {
+				break
+			}
+		} else if e.errors < picked.errors {
+			picked = e
+		}
+	}
+
+	p.next = (p.next + 1) % len(p.endpoints)
+	if picked == nil {
+		return fallback
+	}
+
+	return picked
+}
+
+// report updates the health of the endpoint by the result of a request.
+// It returns true if the endpoint has just been ejected
+func (p *endpointPool) report(e *endpoint, failed bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !failed {
+		if e.errors > 0 {
+			e.errors--
+		}
+		e.healthy = true
+		return false
+	}
+
+	e.errors++
+	if len(p.endpoints) == 1 || !e.healthy {
+		return false
+	}
+
+	e.healthy = false
+	e.ejectedAt = time.Now()
+	return true
+}
+
+// dueProbes returns ejected endpoints which should be probed now and marks them as probing
+func (p *endpointPool) dueProbes() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var due []*endpoint
+	for _, e := range p.endpoints {
+		if !e.healthy && !e.probing && time.Since(e.ejectedAt) >= p.probeInterval {
+			e.probing = true
+			due = append(due, e)
+		}
+	}
+
+	return due
+}
+
+// probed returns the endpoint to the pool if the probe succeeded, otherwise the endpoint stays ejected.
+// The errors of the returned endpoint decay with successful requests
+func (p *endpointPool) probed(e *endpoint, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.probing = false
+	if ok {
+		e.healthy = true
+		return
+	}
+
+	e.ejectedAt = time.Now()
+}
+
+// probeEndpoints starts probes of ejected endpoints in background
+func (c *Client) probeEndpoints() {
+	for _, e := range c.endpoints.dueProbes() {
+		go func(e *endpoint) {
+			ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+			defer cancel()
+
+			res, err := c.sendTo(e, &Request{
+				Context:   ctx,
+				Operation: "Version",
+				Method:    http.MethodGet,
+				Path:      "/version",
+				Header:    http.Header{"User-Agent": []string{c.userAgent}},
+			})
+			if res != nil {
+				res.Body.Close()
+			}
+
+			c.endpoints.probed(e, err == nil && res.StatusCode >= 200 && res.StatusCode <= 299)
+		}(e)
+	}
+}
+
+// isEndpointFailure returns true if the error or the response indicates an unavailable engine node.
+// Error responses of the engine itself, e.g. 500 with a JSON error, are not failures of the node
+func isEndpointFailure(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return res.StatusCode >= 500 && !strings.HasPrefix(res.Header.Get("Content-Type"), "application/json")
+}
+
+// isIdempotent returns true if the request can be safely sent to another node after a failure
+func isIdempotent(method, operation string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	// queries by POST and fetching of external tasks, a task locked by a failed node is unlocked after lock expiration
+	return operation == "ExternalTask.FetchAndLock" ||
+		strings.Contains(operation, ".GetList") ||
+		strings.HasSuffix(operation, "ListPost") ||
+		strings.HasSuffix(operation, "Count") ||
+		strings.HasSuffix(operation, "CountPost")
+}
@@ -0,0 +1,77 @@
+package camunda_client_go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointFailover(t *testing.T) {
+	var healthyRequests int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyRequests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":1}`))
+	}))
+	defer healthy.Close()
+
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	deadUrl := dead.URL
+	dead.Close()
+
+	client := NewClient(ClientOptions{
+		EndpointUrls:  []string{deadUrl, healthy.URL},
+		Timeout:       time.Second,
+		ProbeInterval: time.Hour,
+	})
+
+	// the dead node is tried first and the idempotent request fails over to the healthy node
+	count, err := client.ExternalTask.GetListCount(map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// the dead node is ejected, all requests go to the healthy node
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&healthyRequests))
+}
+
+func TestEndpointNonIdempotentRequestIsNotRetried(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{
+		EndpointUrls: []string{server.URL, server.URL + "/"},
+	})
+
+	key := "demo"
+	_, err := client.ProcessDefinition.StartInstance(QueryProcessDefinitionBy{Key: &key}, ReqStartInstance{})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestEndpointLeastErrors(t *testing.T) {
+	pool := newEndpointPool([]string{"http://a", "http://b"}, EndpointLeastErrors, time.Hour)
+	a, b := pool.endpoints[0], pool.endpoints[1]
+
+	assert.True(t, pool.report(a, true))
+	assert.Empty(t, pool.dueProbes(), "the probe is not due yet")
+	pool.probed(a, true)
+	assert.True(t, a.healthy)
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, b, pool.pick(map[*endpoint]bool{}))
+	}
+
+	pool.report(a, false)
+	assert.Equal(t, 0, a.errors)
+}
@@ -263,6 +263,18 @@ type QueryHandleBPMNError struct {
 	Variables *map[string]Variable `json:"variables"`
 }
 
+// QueryHandleEscalation a query for HandleEscalation request
+type QueryHandleEscalation struct {
+	// The id of the worker that reports the escalation.
+	// Must match the id of the worker who has most recently locked the task
+	WorkerId *string `json:"workerId,omitempty"`
+	// An escalation code that indicates the predefined escalation. Is used to identify the BPMN escalation handler
+	EscalationCode *string `json:"escalationCode,omitempty"`
+	// A JSON object containing the variables which will be passed to the execution.
+	// Each key corresponds to a variable name and each value to a variable value
+	Variables *map[string]Variable `json:"variables,omitempty"`
+}
+
 // QueryHandleFailure a query for HandleFailure request
 type QueryHandleFailure struct {
 	// The id of the worker that reports the failure.
@@ -440,6 +452,16 @@ func (e *ExternalTask) HandleBPMNError(id string, query QueryHandleBPMNError) er
 	return err
 }
 
+// HandleEscalation reports an escalation in the context of a running external task by id.
+// The escalation code must be specified to identify the escalation handler
+func (e *ExternalTask) HandleEscalation(id string, query QueryHandleEscalation) error {
+	res, err := e.client.doPostJson("/external-task/"+id+"/bpmnEscalation", map[string]string{}, &query)
+	if res != nil {
+		res.Body.Close()
+	}
+	return err
+}
+
 // HandleFailure reports a failure to execute an external task by id.
 // A number of retries and a timeout until the task can be retried can be specified.
 // If retries are set to 0, an incident for this task is created
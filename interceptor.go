@@ -0,0 +1,80 @@
+package camunda_client_go
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Request a request to the Camunda REST API passed through the interceptor chain
+type Request struct {
+	// Context of the request, it carries cancellation, deadlines and the span of the request
+	Context context.Context
+	// Operation a name of the client method which makes the request, e.g. `ProcessDefinition.StartInstance`
+	Operation string
+	// HTTP method of the request
+	Method string
+	// Path of the request relative to the endpoint url, e.g. `/process-definition/key/demo/start`
+	Path string
+	// Query parameters of the request
+	Query map[string]string
+	// Headers of the request. User-Agent and Content-Type are already set
+	Header http.Header
+	// Body of the request, nil for requests without body
+	Body []byte
+	// Idempotent the request can be sent to another engine node after a failure of the selected one
+	Idempotent bool
+}
+
+// RoundTripFunc executes a request and returns a raw response of the engine.
+// A response with non 2xx status code is not an error on this level
+type RoundTripFunc func(req *Request) (*http.Response, error)
+
+// Interceptor a middleware for requests to the Camunda REST API.
+// An interceptor may modify the request, call next or return its own response or error
+type Interceptor func(next RoundTripFunc) RoundTripFunc
+
+// chainInterceptors wraps roundTrip by interceptors, the first interceptor is the outermost one
+func chainInterceptors(roundTrip RoundTripFunc, interceptors []Interceptor) RoundTripFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		roundTrip = interceptors[i](roundTrip)
+	}
+
+	return roundTrip
+}
+
+var packagePath = reflect.TypeOf(Client{}).PkgPath()
+
+// operationNames a public names of api types which differ from the type names
+var operationNames = map[string]string{
+	"userTaskApi": "UserTask",
+}
+
+// operationName returns the name of the api method which calls Client, e.g. `ProcessDefinition.StartInstance`
+func operationName() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, packagePath+".(*") {
+			parts := strings.SplitN(strings.TrimPrefix(frame.Function, packagePath+".(*"), ".", 3)
+			if len(parts) >= 2 {
+				typeName := strings.TrimSuffix(parts[0], ")")
+				if typeName != "Client" {
+					if name, ok := operationNames[typeName]; ok {
+						typeName = name
+					}
+
+					return typeName + "." + parts[1]
+				}
+			}
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
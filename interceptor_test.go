@@ -0,0 +1,80 @@
+package camunda_client_go
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/process-definition/key/demo/start", r.URL.Path)
+		assert.Equal(t, "injected", r.Header.Get("X-Test"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"instance-id"}`))
+	}))
+	defer server.Close()
+
+	var calls []string
+	var operation string
+	var body []byte
+	client := NewClient(ClientOptions{
+		EndpointUrl: server.URL,
+		Interceptors: []Interceptor{
+			func(next RoundTripFunc) RoundTripFunc {
+				return func(req *Request) (*http.Response, error) {
+					calls = append(calls, "first")
+					operation = req.Operation
+					body = req.Body
+					return next(req)
+				}
+			},
+			func(next RoundTripFunc) RoundTripFunc {
+				return func(req *Request) (*http.Response, error) {
+					calls = append(calls, "second")
+					req.Header.Set("X-Test", "injected")
+					res, err := next(req)
+					assert.Equal(t, http.StatusOK, res.StatusCode)
+					return res, err
+				}
+			},
+		},
+	})
+
+	key := "demo"
+	res, err := client.ProcessDefinition.StartInstance(QueryProcessDefinitionBy{Key: &key}, ReqStartInstance{})
+	assert.NoError(t, err)
+	assert.Equal(t, "instance-id", res.Id)
+	assert.Equal(t, []string{"first", "second"}, calls)
+	assert.Equal(t, "ProcessDefinition.StartInstance", operation)
+	assert.Contains(t, string(body), "{")
+}
+
+func TestInterceptorFaultInjection(t *testing.T) {
+	errInjected := errors.New("injected")
+	client := NewClient(ClientOptions{
+		Interceptors: []Interceptor{
+			func(next RoundTripFunc) RoundTripFunc {
+				return func(req *Request) (*http.Response, error) {
+					if req.Operation == "UserTask.Complete" {
+						return nil, errInjected
+					}
+
+					return &http.Response{
+						StatusCode: http.StatusNoContent,
+						Header:     http.Header{},
+						Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+					}, nil
+				}
+			},
+		},
+	})
+
+	assert.ErrorIs(t, client.UserTask.Complete("task-id", QueryUserTaskComplete{}), errInjected)
+	assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+}
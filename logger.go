@@ -0,0 +1,193 @@
+package camunda_client_go
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger a leveled structured logger used by the client and the external task processor.
+// keysAndValues are alternating keys and values, e.g. `"topic", "PrintHello", "taskId", id`
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// LogLevel a level of log messages
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// String returns the name of the level
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// NopLogger a logger which logs nothing
+type NopLogger struct{}
+
+func (NopLogger) Debug(string, ...interface{}) {}
+
+func (NopLogger) Info(string, ...interface{}) {}
+
+func (NopLogger) Warn(string, ...interface{}) {}
+
+func (NopLogger) Error(string, ...interface{}) {}
+
+// NewStdLogger returns a Logger which writes messages of level and above to the standard library logger
+// in the format `LEVEL message key=value ...`. If l is nil, the standard logger of the log package is used
+func NewStdLogger(l *log.Logger, level LogLevel) Logger {
+	if l == nil {
+		l = log.New(log.Writer(), log.Prefix(), log.Flags())
+	}
+
+	return &stdLogger{logger: l, level: level}
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+}
+
+func (l *stdLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelDebug, msg, keysAndValues)
+}
+
+func (l *stdLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelInfo, msg, keysAndValues)
+}
+
+func (l *stdLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelWarn, msg, keysAndValues)
+}
+
+func (l *stdLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(LogLevelError, msg, keysAndValues)
+}
+
+func (l *stdLogger) log(level LogLevel, msg string, keysAndValues []interface{}) {
+	if level < l.level {
+		return
+	}
+
+	l.logger.Print(level.String() + " " + formatMessage(msg, keysAndValues))
+}
+
+// NewErrorFuncLogger returns a Logger which passes warnings and errors to fn as errors,
+// it adapts the `logger func(err error)` of the processor
+func NewErrorFuncLogger(fn func(err error)) Logger {
+	return errorFuncLogger(fn)
+}
+
+type errorFuncLogger func(err error)
+
+func (l errorFuncLogger) Debug(string, ...interface{}) {}
+
+func (l errorFuncLogger) Info(string, ...interface{}) {}
+
+func (l errorFuncLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l(errors.New(formatMessage(msg, keysAndValues)))
+}
+
+func (l errorFuncLogger) Error(msg string, keysAndValues ...interface{}) {
+	l(errors.New(formatMessage(msg, keysAndValues)))
+}
+
+// SugaredLogger a logger with methods of go.uber.org/zap.SugaredLogger
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewSugaredLogger returns a Logger which writes to the zap sugared logger
+func NewSugaredLogger(l SugaredLogger) Logger {
+	return &sugaredLogger{logger: l}
+}
+
+type sugaredLogger struct {
+	logger SugaredLogger
+}
+
+func (l *sugaredLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debugw(msg, keysAndValues...)
+}
+
+func (l *sugaredLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Infow(msg, keysAndValues...)
+}
+
+func (l *sugaredLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.logger.Warnw(msg, keysAndValues...)
+}
+
+func (l *sugaredLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Errorw(msg, keysAndValues...)
+}
+
+// KeyValueLogger a logger with the method of github.com/go-kit/log.Logger
+type KeyValueLogger interface {
+	Log(keysAndValues ...interface{}) error
+}
+
+// NewKeyValueLogger returns a Logger which writes to the go-kit logger with `level` and `msg` keys
+func NewKeyValueLogger(l KeyValueLogger) Logger {
+	return &keyValueLogger{logger: l}
+}
+
+type keyValueLogger struct {
+	logger KeyValueLogger
+}
+
+func (l *keyValueLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log("debug", msg, keysAndValues)
+}
+
+func (l *keyValueLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log("info", msg, keysAndValues)
+}
+
+func (l *keyValueLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log("warn", msg, keysAndValues)
+}
+
+func (l *keyValueLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log("error", msg, keysAndValues)
+}
+
+func (l *keyValueLogger) log(level, msg string, keysAndValues []interface{}) {
+	_ = l.logger.Log(append([]interface{}{"level", level, "msg", msg}, keysAndValues...)...)
+}
+
+// formatMessage formats the message and fields as `message key=value ...`
+func formatMessage(msg string, keysAndValues []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&b, " %v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", keysAndValues[i])
+		}
+	}
+
+	return b.String()
+}
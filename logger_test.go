@@ -0,0 +1,114 @@
+package camunda_client_go
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdLoggerFiltersLevels(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := NewStdLogger(log.New(output, "", 0), LogLevelWarn)
+
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("task failed", "topic", "charge", "retries", 2)
+	logger.Error("fetch failed", "error", "timeout")
+
+	assert.Equal(t, "WARN task failed topic=charge retries=2\nERROR fetch failed error=timeout\n", output.String())
+}
+
+func TestFormatMessage(t *testing.T) {
+	assert.Equal(t, "message", formatMessage("message", nil))
+	assert.Equal(t, "message topic=charge", formatMessage("message", []interface{}{"topic", "charge"}))
+	assert.Equal(t, "message topic=charge dangling", formatMessage("message", []interface{}{"topic", "charge", "dangling"}), "a key without a value is logged alone")
+}
+
+func TestErrorFuncLogger(t *testing.T) {
+	var errs []error
+	logger := NewErrorFuncLogger(func(err error) {
+		errs = append(errs, err)
+	})
+
+	logger.Debug("debug message", "key", "value")
+	logger.Info("info message", "key", "value")
+	logger.Warn("task failed", "topic", "charge")
+	logger.Error("fetch failed", "error", "timeout")
+
+	if assert.Len(t, errs, 2, "debug and info messages are dropped") {
+		assert.EqualError(t, errs[0], "task failed topic=charge")
+		assert.EqualError(t, errs[1], "fetch failed error=timeout")
+	}
+}
+
+// recordingSugaredLogger records calls like go.uber.org/zap.SugaredLogger
+type recordingSugaredLogger struct {
+	calls []string
+}
+
+func (l *recordingSugaredLogger) record(level, msg string, keysAndValues []interface{}) {
+	l.calls = append(l.calls, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+func (l *recordingSugaredLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.record("debug", msg, keysAndValues)
+}
+
+func (l *recordingSugaredLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.record("info", msg, keysAndValues)
+}
+
+func (l *recordingSugaredLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.record("warn", msg, keysAndValues)
+}
+
+func (l *recordingSugaredLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.record("error", msg, keysAndValues)
+}
+
+func TestSugaredLogger(t *testing.T) {
+	sugared := &recordingSugaredLogger{}
+	logger := NewSugaredLogger(sugared)
+
+	logger.Debug("debug message", "key", 1)
+	logger.Info("info message")
+	logger.Warn("warn message", "key", "value")
+	logger.Error("error message", "dangling")
+
+	assert.Equal(t, []string{
+		"debug debug message [key 1]",
+		"info info message []",
+		"warn warn message [key value]",
+		"error error message [dangling]",
+	}, sugared.calls)
+}
+
+// recordingKeyValueLogger records calls like github.com/go-kit/log.Logger
+type recordingKeyValueLogger struct {
+	calls [][]interface{}
+}
+
+func (l *recordingKeyValueLogger) Log(keysAndValues ...interface{}) error {
+	l.calls = append(l.calls, keysAndValues)
+	return nil
+}
+
+func TestKeyValueLogger(t *testing.T) {
+	kv := &recordingKeyValueLogger{}
+	logger := NewKeyValueLogger(kv)
+
+	logger.Debug("debug message")
+	logger.Info("info message", "topic", "charge")
+	logger.Warn("warn message")
+	logger.Error("error message", "error", "timeout", "dangling")
+
+	assert.Equal(t, [][]interface{}{
+		{"level", "debug", "msg", "debug message"},
+		{"level", "info", "msg", "info message", "topic", "charge"},
+		{"level", "warn", "msg", "warn message"},
+		{"level", "error", "msg", "error message", "error", "timeout", "dangling"},
+	}, kv.calls)
+}
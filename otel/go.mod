@@ -0,0 +1,13 @@
+module github.com/wurenquyu/camunda-client-go/otel
+
+go 1.16
+
+require (
+	github.com/stretchr/testify v1.7.1
+	github.com/wurenquyu/camunda-client-go/v3 v3.1.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
+
+replace github.com/wurenquyu/camunda-client-go/v3 => ../
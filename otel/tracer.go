@@ -0,0 +1,94 @@
+// Package otel an OpenTelemetry implementation of camunda_client_go.Tracer
+package otel
+
+import (
+	"context"
+	"fmt"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName a name of the instrumentation library
+const InstrumentationName = "github.com/wurenquyu/camunda-client-go/otel"
+
+// Tracer creates OpenTelemetry spans for the client and the external task processor
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer a create new instance Tracer. If provider is nil, the global tracer provider is used
+func NewTracer(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer: provider.Tracer(InstrumentationName),
+	}
+}
+
+// Start starts a new span of the kind as a child of the span in ctx
+func (t *Tracer) Start(ctx context.Context, name string, kind camundaclientgo.SpanKind) (context.Context, camundaclientgo.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(toSpanKind(kind)))
+	return ctx, &Span{span: span}
+}
+
+func toSpanKind(kind camundaclientgo.SpanKind) trace.SpanKind {
+	switch kind {
+	case camundaclientgo.SpanKindClient:
+		return trace.SpanKindClient
+	case camundaclientgo.SpanKindConsumer:
+		return trace.SpanKindConsumer
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+// Span a camunda_client_go.Span backed by an OpenTelemetry span
+type Span struct {
+	span trace.Span
+}
+
+// SetAttributes sets attributes of the span
+func (s *Span) SetAttributes(attributes ...camundaclientgo.Attribute) {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		kvs = append(kvs, toKeyValue(a))
+	}
+
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError records the error and sets the error status of the span
+func (s *Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End completes the span
+func (s *Span) End() {
+	s.span.End()
+}
+
+func toKeyValue(a camundaclientgo.Attribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case []string:
+		return attribute.StringSlice(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
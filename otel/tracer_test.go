@@ -0,0 +1,65 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestClientSpans(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"type":"InvalidRequestException","message":"invalid"}`))
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{
+		EndpointUrl: server.URL,
+		Tracer:      NewTracer(provider),
+	})
+
+	key := "demo"
+	_, err := client.ProcessDefinition.StartInstance(
+		camundaclientgo.QueryProcessDefinitionBy{Key: &key},
+		camundaclientgo.ReqStartInstance{},
+	)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "ProcessDefinition.StartInstance", spans[0].Name())
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Attributes(), attribute.String(camundaclientgo.AttributeErrorType, "InvalidRequestException"))
+		assert.Contains(t, spans[0].Attributes(), attribute.Int(camundaclientgo.AttributeHttpStatusCode, http.StatusBadRequest))
+		assert.Contains(t, spans[0].Attributes(), attribute.String(camundaclientgo.AttributeEnginePath, "/process-definition/key/demo/start"))
+	}
+}
+
+func TestSpanKinds(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	for _, kind := range []camundaclientgo.SpanKind{camundaclientgo.SpanKindConsumer, camundaclientgo.SpanKindInternal} {
+		_, span := tracer.Start(context.Background(), "span", kind)
+		span.End()
+	}
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, trace.SpanKindConsumer, spans[0].SpanKind())
+		assert.Equal(t, trace.SpanKindInternal, spans[1].SpanKind())
+	}
+}
@@ -0,0 +1,191 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// DefaultLockMargin the default safety margin between the deadline of a handler and the lock expiration of its task
+const DefaultLockMargin = time.Second
+
+// deadlineContext a context of a handler whose deadline moves when the lock of its task is extended.
+// It is done when the deadline is exceeded or the context is cancelled, e.g. if Shutdown times out
+type deadlineContext struct {
+	context.Context
+	// lockMargin the margin of the lock expiration, negative if the lock doesn't limit the handler
+	lockMargin time.Duration
+	// timeout the deadline by the timeout of the handler, zero if the handler has no timeout
+	timeout time.Time
+
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	// extending counts lock extensions in progress, the deadline is not exceeded while the lock is extended
+	extending int
+	done      chan struct{}
+	err       error
+}
+
+// Deadline returns the current deadline of the handler
+func (d *deadlineContext) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.deadline, !d.deadline.IsZero()
+}
+
+// Done returns a channel closed when the deadline is exceeded or the context is cancelled
+func (d *deadlineContext) Done() <-chan struct{} {
+	return d.done
+}
+
+// Err returns context.DeadlineExceeded or context.Canceled after the context is done
+func (d *deadlineContext) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.err
+}
+
+func (d *deadlineContext) cancel(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cancelLocked(err)
+}
+
+func (d *deadlineContext) cancelLocked(err error) {
+	if d.err != nil {
+		return
+	}
+	d.err = err
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	close(d.done)
+}
+
+// setDeadlineLocked moves the deadline, a deadline in the past is exceeded immediately
+func (d *deadlineContext) setDeadlineLocked(deadline time.Time) {
+	if d.err != nil {
+		return
+	}
+	d.deadline = deadline
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if deadline.IsZero() {
+		return
+	}
+	d.timer = time.AfterFunc(time.Until(deadline), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		if d.extending == 0 && d.deadline.Equal(deadline) {
+			d.cancelLocked(context.DeadlineExceeded)
+		}
+	})
+}
+
+// extendLock holds the deadline while the lock is extended. The returned func moves the deadline to the new lock
+// expiration minus the lock margin if the lock is extended, the timeout of the handler still applies
+func (d *deadlineContext) extendLock() func(expiration time.Time, extended bool) {
+	d.mu.Lock()
+	d.extending++
+	d.mu.Unlock()
+
+	return func(expiration time.Time, extended bool) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		d.extending--
+		deadline := d.deadline
+		if extended && d.lockMargin >= 0 {
+			deadline = earliest(lockDeadline(expiration, d.lockMargin), d.timeout)
+		}
+		d.setDeadlineLocked(deadline)
+	}
+}
+
+// lockDeadline returns the lock expiration minus the margin, short locks keep at least a half of the remaining
+// time for the handler
+func lockDeadline(expiration time.Time, margin time.Duration) time.Time {
+	if remaining := time.Until(expiration); remaining > 0 && margin > remaining/2 {
+		margin = remaining / 2
+	}
+
+	return expiration.Add(-margin)
+}
+
+// earliest returns the earlier of the deadlines, zero is no deadline
+func earliest(deadline, other time.Time) time.Time {
+	if deadline.IsZero() || (!other.IsZero() && other.Before(deadline)) {
+		return other
+	}
+
+	return deadline
+}
+
+// handlerContext returns a context of the handler with the deadline of the task: the lock expiration minus
+// the lock margin or the timeout of the handler, whichever is earlier. The deadline moves when the handler
+// extends the lock. The context is cancelled if Shutdown times out
+func (p *Processor) handlerContext(ctx context.Context, task *camundaclientgo.ResLockedExternalTask, pool *handlerPool) (*deadlineContext, context.CancelFunc) {
+	d := &deadlineContext{Context: ctx, lockMargin: -1, done: make(chan struct{})}
+	if pool.timeout > 0 {
+		d.timeout = time.Now().Add(pool.timeout)
+	}
+
+	deadline := d.timeout
+	if !p.options.DisableLockDeadline && task.LockExpirationTime != "" {
+		lockExpiration, err := time.Parse(camundaclientgo.DefaultDateTimeFormat, task.LockExpirationTime)
+		if err != nil {
+			p.logger.Warn("can't parse lock expiration time of the task", append(taskLogFields(task), "error", err)...)
+		} else {
+			d.lockMargin = p.options.LockMargin
+			if d.lockMargin <= 0 {
+				d.lockMargin = DefaultLockMargin
+			}
+			deadline = earliest(lockDeadline(lockExpiration, d.lockMargin), d.timeout)
+		}
+	}
+	d.mu.Lock()
+	d.setDeadlineLocked(deadline)
+	d.mu.Unlock()
+
+	go func() {
+		select {
+		case <-p.handlersCtx.Done():
+			d.cancel(context.Canceled)
+		case <-ctx.Done():
+			d.cancel(ctx.Err())
+		case <-d.done:
+		}
+	}()
+
+	return d, func() { d.cancel(context.Canceled) }
+}
+
+// withDeadline returns a handler which returns an error if the next handler does not return before the deadline
+// of its context. The next handler is abandoned: its reports are rejected by ErrAbandoned and its worker is held
+// until it returns, OnLockLost is called. The error is replaced by Options.DeadlineError if it is set
+func (p *Processor) withDeadline(next Handler) Handler {
+	return func(ctx *Context) error {
+		if abandoned, err := runAbandonable(ctx, ctx.Context(), next); !abandoned {
+			return err
+		}
+
+		deadline, _ := ctx.Context().Deadline()
+		err := fmt.Errorf("handler did not return before the deadline %s: %w", deadline.Format(camundaclientgo.DefaultDateTimeFormat), context.DeadlineExceeded)
+		p.logger.Warn("handler overran the deadline", append(taskLogFields(ctx.Task), "deadline", deadline)...)
+		ctx.lockLost(TaskEvent{Task: ctx.Task, Time: time.Now(), Err: err})
+		if p.options.DeadlineError != nil {
+			return p.options.DeadlineError(ctx, err)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,190 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestHandlerContextDeadline(t *testing.T) {
+	p := newTestProcessor(t, &Options{LockMargin: 5 * time.Second}, http.NotFound)
+	pool := &handlerPool{}
+	lockExpiration := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	task := &camundaclientgo.ResLockedExternalTask{LockExpirationTime: lockExpiration.Format(camundaclientgo.DefaultDateTimeFormat)}
+	deadline := func() (time.Time, bool) {
+		ctx, cancel := p.handlerContext(context.Background(), task, pool)
+		defer cancel()
+		return ctx.Deadline()
+	}
+
+	d, ok := deadline()
+	assert.True(t, ok)
+	assert.True(t, lockExpiration.Add(-5*time.Second).Equal(d))
+
+	pool.timeout = time.Second
+	d, _ = deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Second), d, 100*time.Millisecond)
+
+	pool.timeout = 0
+	task.LockExpirationTime = time.Now().Add(2 * time.Second).Format(camundaclientgo.DefaultDateTimeFormat)
+	d, _ = deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Second), d, 100*time.Millisecond, "the margin is at most a half of the lock")
+
+	p.options.DisableLockDeadline = true
+	_, ok = deadline()
+	assert.False(t, ok)
+
+	p.options.DisableLockDeadline = false
+	task.LockExpirationTime = "invalid"
+	_, ok = deadline()
+	assert.False(t, ok)
+}
+
+func TestHandlerContextDeadlineMovesWithLock(t *testing.T) {
+	p := newTestProcessor(t, &Options{}, http.NotFound)
+	pool := &handlerPool{timeout: time.Hour}
+	task := &camundaclientgo.ResLockedExternalTask{LockExpirationTime: time.Now().Add(100 * time.Millisecond).Format(camundaclientgo.DefaultDateTimeFormat)}
+	startedAt := time.Now()
+	ctx, cancel := p.handlerContext(context.Background(), task, pool)
+	defer cancel()
+
+	extended := ctx.extendLock()
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, ctx.Err(), "the deadline is held while the lock is extended")
+	extended(time.Now().Add(time.Minute), true)
+	deadline, _ := ctx.Deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Minute-DefaultLockMargin), deadline, 100*time.Millisecond)
+
+	extended = ctx.extendLock()
+	extended(time.Now().Add(2*time.Hour), true)
+	deadline, _ = ctx.Deadline()
+	assert.WithinDuration(t, startedAt.Add(time.Hour), deadline, 100*time.Millisecond, "the timeout still applies")
+
+	extended = ctx.extendLock()
+	extended(time.Now().Add(-time.Second), true)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the deadline is not exceeded")
+	}
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestWithDeadline(t *testing.T) {
+	p := newTestProcessor(t, &Options{}, http.NotFound)
+	client := &recordingClient{}
+	task := &camundaclientgo.ResLockedExternalTask{Id: "task"}
+	pool := &handlerPool{timeout: 10 * time.Millisecond}
+	ctx, cancel := p.handlerContext(context.Background(), task, pool)
+	defer cancel()
+
+	// the handler overruns the deadline and reports later
+	var lateErr error
+	slowHandler := func(ctx *Context) error {
+		<-ctx.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		lateErr = ctx.Complete(QueryComplete{})
+		return nil
+	}
+
+	handlerCtx := NewContext(ctx, task, client)
+	var lost []TaskEvent
+	handlerCtx.hooks = &Hooks{OnLockLost: func(event TaskEvent) { lost = append(lost, event) }}
+	err := Run(handlerCtx, p.withDeadline(slowHandler), nil)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	if assert.Len(t, lost, 1, "the overrun loses the lock") {
+		assert.True(t, errors.Is(lost[0].Err, context.DeadlineExceeded))
+	}
+	assert.Equal(t, OutcomeFailure, handlerCtx.Outcome())
+	assert.Equal(t, ErrAbandoned, lateErr, "Run returns after the abandoned handler")
+	assert.Empty(t, client.completes)
+	require.Len(t, client.failures, 1)
+	assert.Contains(t, *client.failures[0].ErrorMessage, "handler did not return before the deadline")
+
+	p.options.DeadlineError = func(ctx *Context, err error) error {
+		return Incident(err, "the handler is too slow")
+	}
+	ctx, cancel = p.handlerContext(context.Background(), task, pool)
+	defer cancel()
+	err = Run(NewContext(ctx, task, client), p.withDeadline(slowHandler), nil)
+	assert.Error(t, err)
+	require.Len(t, client.failures, 2)
+	assert.Equal(t, 0, *client.failures[1].Retries)
+	assert.Equal(t, "the handler is too slow", *client.failures[1].ErrorDetails)
+}
+
+func TestHandlerContextCancelledByShutdown(t *testing.T) {
+	p := newTestProcessor(t, &Options{}, http.NotFound)
+	ctx, cancel := p.handlerContext(context.Background(), &camundaclientgo.ResLockedExternalTask{}, &handlerPool{})
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+
+	p.reserved = 1
+	shutdownCtx, cancelShutdown := context.WithCancel(context.Background())
+	cancelShutdown()
+	assert.Error(t, p.Shutdown(shutdownCtx))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the context of the handler is not cancelled")
+	}
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestDeadlineHoldsWorker(t *testing.T) {
+	failed := make(chan struct{}, 1)
+	var completes int32
+	p := newTestProcessor(t, &Options{DisableLockDeadline: true}, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/failure"):
+			failed <- struct{}{}
+		case strings.HasSuffix(r.URL.Path, "/complete"):
+			atomic.AddInt32(&completes, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	release := make(chan struct{})
+	var lateErr error
+	pool := &handlerPool{capacity: 1, timeout: 10 * time.Millisecond, handler: func(ctx *Context) error {
+		<-ctx.Context().Done()
+		<-release
+		lateErr = ctx.Complete(QueryComplete{})
+		return lateErr
+	}}
+
+	handled := make(chan struct{})
+	go func() {
+		p.handleTask(&camundaclientgo.ResLockedExternalTask{Id: "task", TopicName: "topic"}, pool)
+		close(handled)
+	}()
+
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failure is not reported after the deadline")
+	}
+	select {
+	case <-handled:
+		t.Fatal("the worker is released while the handler is running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pool.inFlight))
+
+	close(release)
+	<-handled
+	assert.Equal(t, ErrAbandoned, lateErr)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&completes))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&pool.inFlight))
+}
@@ -0,0 +1,349 @@
+package processor
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+const (
+	// DefaultDedupSize the default number of records of the in-memory dedup store
+	DefaultDedupSize = 10000
+	// DefaultDedupTTL the default time a record of the file dedup store is kept
+	DefaultDedupTTL = 24 * time.Hour
+)
+
+// DedupRecord a recorded result of a task, only the query of the type is set
+type DedupRecord struct {
+	Key        string                 `json:"key"`
+	TaskId     string                 `json:"taskId"`
+	Type       ReportType             `json:"type"`
+	Complete   *QueryComplete         `json:"complete,omitempty"`
+	BPMNError  *QueryHandleBPMNError  `json:"bpmnError,omitempty"`
+	Escalation *QueryHandleEscalation `json:"escalation,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+}
+
+// DedupStore a store of results of processed tasks by keys
+type DedupStore interface {
+	// Get returns the record of the key, nil if the key is not processed
+	Get(key string) (*DedupRecord, error)
+	// Put saves the record, it replaces a record of the same key
+	Put(record *DedupRecord) error
+}
+
+// DedupOptions options of the deduplication of tasks
+type DedupOptions struct {
+	// a store of results (default: a MemoryDedupStore of DefaultDedupSize records)
+	Store DedupStore
+	// Key returns the key of the task, e.g. the business key and the activity id to deduplicate tasks
+	// of restarted process instances (default: the task id).
+	//
+	// The default key only deduplicates the same task fetched again, e.g. after its lock expired. A new task
+	// of the same activity, e.g. after a process instance is restarted or the activity is retried by a BPMN
+	// retry loop, has a new id and runs the handler again
+	Key func(task *camundaclientgo.ResLockedExternalTask) string
+}
+
+// Dedup returns a middleware which runs the next handler once per key of the task, see DedupOptions.Key.
+// If the key is processed, the recorded result (a completion, a BPMN error or an escalation) is reported again
+// instead of running the handler. A result is recorded before it is reported, so a result which is not delivered
+// is reported again too. A task with the key being handled by another worker of the processor is skipped.
+// Failures are not recorded, so failed tasks are retried
+func Dedup(options DedupOptions) Middleware {
+	store := options.Store
+	if store == nil {
+		store = NewMemoryDedupStore(DefaultDedupSize)
+	}
+	key := options.Key
+	if key == nil {
+		key = func(task *camundaclientgo.ResLockedExternalTask) string {
+			return task.Id
+		}
+	}
+
+	var mu sync.Mutex
+	inProgress := map[string]bool{}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			taskKey := key(ctx.Task)
+
+			mu.Lock()
+			if inProgress[taskKey] {
+				mu.Unlock()
+				return nil
+			}
+			inProgress[taskKey] = true
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(inProgress, taskKey)
+				mu.Unlock()
+			}()
+
+			record, err := store.Get(taskKey)
+			if err != nil {
+				return fmt.Errorf("can't get dedup record of %s: %w", taskKey, err)
+			}
+			if record != nil {
+				return replay(ctx, record)
+			}
+
+			recorder := &dedupRecorder{ExternalTaskClient: ctx.client, store: store, key: taskKey, taskId: ctx.Task.Id}
+			recordCtx := *ctx
+			recordCtx.client = recorder
+
+			err = next(&recordCtx)
+			// BPMN errors and escalations returned by the handler are reported by Run after the middleware
+			var bpmnError *BPMNError
+			var escalation *Escalation
+			switch {
+			case errors.As(err, &bpmnError):
+				query := bpmnError.query()
+				if putErr := recorder.put(ReportBPMNError, &DedupRecord{BPMNError: &query}); putErr != nil {
+					return putErr
+				}
+			case errors.As(err, &escalation):
+				query := escalation.query()
+				if putErr := recorder.put(ReportEscalation, &DedupRecord{Escalation: &query}); putErr != nil {
+					return putErr
+				}
+			}
+
+			return err
+		}
+	}
+}
+
+// replay reports the recorded result of the task
+func replay(ctx *Context, record *DedupRecord) error {
+	switch record.Type {
+	case ReportComplete:
+		return ctx.Complete(*record.Complete)
+	case ReportBPMNError:
+		return ctx.HandleBPMNError(*record.BPMNError)
+	case ReportEscalation:
+		return ctx.HandleEscalation(*record.Escalation)
+	}
+
+	return fmt.Errorf("unknown dedup record type %q", record.Type)
+}
+
+// dedupRecorder records reports of a handler in the store before they are sent
+type dedupRecorder struct {
+	ExternalTaskClient
+	store  DedupStore
+	key    string
+	taskId string
+}
+
+// put saves the record of the report type
+func (r *dedupRecorder) put(reportType ReportType, record *DedupRecord) error {
+	record.Key, record.TaskId, record.Type, record.CreatedAt = r.key, r.taskId, reportType, time.Now()
+	if err := r.store.Put(record); err != nil {
+		return fmt.Errorf("can't put dedup record of %s: %w", r.key, err)
+	}
+
+	return nil
+}
+
+func (r *dedupRecorder) Complete(id string, query camundaclientgo.QueryComplete) error {
+	record := &DedupRecord{Complete: &QueryComplete{Variables: query.Variables, LocalVariables: query.LocalVariables}}
+	if err := r.put(ReportComplete, record); err != nil {
+		return err
+	}
+
+	return r.ExternalTaskClient.Complete(id, query)
+}
+
+func (r *dedupRecorder) HandleBPMNError(id string, query camundaclientgo.QueryHandleBPMNError) error {
+	record := &DedupRecord{BPMNError: &QueryHandleBPMNError{ErrorCode: query.ErrorCode, ErrorMessage: query.ErrorMessage, Variables: query.Variables}}
+	if err := r.put(ReportBPMNError, record); err != nil {
+		return err
+	}
+
+	return r.ExternalTaskClient.HandleBPMNError(id, query)
+}
+
+func (r *dedupRecorder) HandleEscalation(id string, query camundaclientgo.QueryHandleEscalation) error {
+	record := &DedupRecord{Escalation: &QueryHandleEscalation{EscalationCode: query.EscalationCode, Variables: query.Variables}}
+	if err := r.put(ReportEscalation, record); err != nil {
+		return err
+	}
+
+	return r.ExternalTaskClient.HandleEscalation(id, query)
+}
+
+// MemoryDedupStore an in-memory dedup store which evicts the least recently used records
+type MemoryDedupStore struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	records map[string]*list.Element
+}
+
+// NewMemoryDedupStore returns an in-memory store of size records at most
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	if size < 1 {
+		size = DefaultDedupSize
+	}
+
+	return &MemoryDedupStore{size: size, order: list.New(), records: map[string]*list.Element{}}
+}
+
+// Get returns the record of the key and marks it as recently used
+func (s *MemoryDedupStore) Get(key string) (*DedupRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.records[key]
+	if !ok {
+		return nil, nil
+	}
+	s.order.MoveToFront(element)
+
+	return element.Value.(*DedupRecord), nil
+}
+
+// Put saves the record and evicts the least recently used record if the store is full
+func (s *MemoryDedupStore) Put(record *DedupRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.records[record.Key]; ok {
+		element.Value = record
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.records[record.Key] = s.order.PushFront(record)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.records, oldest.Value.(*DedupRecord).Key)
+	}
+
+	return nil
+}
+
+// FileDedupStore a dedup store which saves every record to a JSON file in the directory,
+// records older than the TTL are expired
+type FileDedupStore struct {
+	dir string
+	ttl time.Duration
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// NewFileDedupStore returns a file store in the directory which keeps records for the ttl (default: DefaultDedupTTL),
+// the directory is created if it doesn't exist
+func NewFileDedupStore(dir string, ttl time.Duration) (*FileDedupStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't create dedup directory: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = DefaultDedupTTL
+	}
+
+	return &FileDedupStore{dir: dir, ttl: ttl, prunedAt: time.Now()}, nil
+}
+
+// Get reads the record of the key, an expired record is deleted
+func (s *FileDedupStore) Get(key string) (*DedupRecord, error) {
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.expired(info) {
+		return nil, removeFile(path)
+	}
+
+	// #nosec G304 The path is in the directory of the store, keys are escaped by path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := &DedupRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Put writes the record to a temporary file and renames it, so a crash doesn't leave a partial record.
+// Expired records are pruned at most once per a half of the TTL
+func (s *FileDedupStore) Put(record *DedupRecord) error {
+	if err := writeJsonFile(s.path(record.Key), record); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	prune := time.Since(s.prunedAt) >= s.ttl/2
+	if prune {
+		s.prunedAt = time.Now()
+	}
+	s.mu.Unlock()
+	if prune {
+		// a failed prune is repeated by the next one, the record is saved
+		_ = s.Prune()
+	}
+
+	return nil
+}
+
+// Prune deletes expired records
+func (s *FileDedupStore) Prune() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") || !s.expired(file) {
+			continue
+		}
+		if err := removeFile(filepath.Join(s.dir, file.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FileDedupStore) expired(info os.FileInfo) bool {
+	return time.Since(info.ModTime()) > s.ttl
+}
+
+func (s *FileDedupStore) path(key string) string {
+	return filepath.Join(s.dir, url.PathEscape(key)+".json")
+}
+
+// removeFile removes the file, it does nothing if the file doesn't exist
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package processor
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestDedupReplaysResults(t *testing.T) {
+	calls := 0
+	handler := Dedup(DedupOptions{})(func(ctx *Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("failed")
+		}
+		return ctx.Complete(QueryComplete{Variables: &map[string]camundaclientgo.Variable{
+			"invoice": {Value: "INV-1", Type: "String"},
+		}})
+	})
+
+	// failures are not recorded
+	client := &recordingClient{}
+	assert.Error(t, Run(newTestContext(client), handler, nil))
+	require.NoError(t, Run(newTestContext(client), handler, nil))
+	assert.Equal(t, 2, calls)
+
+	ctx := newTestContext(client)
+	require.NoError(t, Run(ctx, handler, nil))
+	assert.Equal(t, 2, calls, "the handler is not run again")
+	assert.Equal(t, OutcomeComplete, ctx.Outcome())
+	require.Len(t, client.completes, 2)
+	assert.Equal(t, "INV-1", (*client.completes[1].Variables)["invoice"].Value)
+	assert.Equal(t, "worker", *client.completes[1].WorkerId)
+}
+
+// unavailableClient fails completions like an unavailable engine
+type unavailableClient struct {
+	recordingClient
+}
+
+func (c *unavailableClient) Complete(string, camundaclientgo.QueryComplete) error {
+	return errUnavailable
+}
+
+func TestDedupRecordsBeforeReports(t *testing.T) {
+	calls := 0
+	handler := Dedup(DedupOptions{})(func(ctx *Context) error {
+		calls++
+		return ctx.Complete(QueryComplete{})
+	})
+
+	// the completion is not delivered, the task is fetched again after the lock expired
+	assert.Error(t, Run(newTestContext(&unavailableClient{}), handler, nil))
+	client := &recordingClient{}
+	require.NoError(t, Run(newTestContext(client), handler, nil))
+	assert.Equal(t, 1, calls, "the recorded result is reported again")
+	assert.Len(t, client.completes, 1)
+}
+
+func TestDedupRecordsReturnedErrors(t *testing.T) {
+	calls := 0
+	handler := Dedup(DedupOptions{})(func(ctx *Context) error {
+		calls++
+		if calls == 1 {
+			return NewBPMNError("declined", "the card is declined")
+		}
+		return NewEscalation("manual")
+	})
+
+	client := &fakeClient{}
+	var bpmnError *BPMNError
+	assert.True(t, errors.As(Run(newTestContext(client), handler, nil), &bpmnError))
+	ctx := newTestContext(client)
+	require.NoError(t, Run(ctx, handler, nil))
+	assert.Equal(t, 1, calls, "the handler is not run again")
+	assert.Equal(t, OutcomeBPMNError, ctx.Outcome())
+	require.Len(t, client.bpmnErrors, 2)
+	assert.Equal(t, "declined", *client.bpmnErrors[1].ErrorCode)
+}
+
+func TestDedupKeyAndConcurrentTasks(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := Dedup(DedupOptions{
+		Key: func(task *camundaclientgo.ResLockedExternalTask) string {
+			return task.BusinessKey
+		},
+	})(func(ctx *Context) error {
+		close(started)
+		<-release
+		return ctx.HandleBPMNError(QueryHandleBPMNError{ErrorCode: strPtr("declined")})
+	})
+
+	first := newTestContext(&fakeClient{})
+	first.Task.BusinessKey = "order-1"
+	done := make(chan error)
+	go func() {
+		done <- Run(first, handler, nil)
+	}()
+	<-started
+
+	client := &fakeClient{}
+	concurrent := newTestContext(client)
+	concurrent.Task.Id, concurrent.Task.BusinessKey = "another-task", "order-1"
+	require.NoError(t, Run(concurrent, handler, nil))
+	assert.Equal(t, OutcomeNone, concurrent.Outcome(), "the task is skipped")
+
+	close(release)
+	assert.NoError(t, <-done)
+
+	replayed := newTestContext(client)
+	replayed.Task.Id, replayed.Task.BusinessKey = "another-task", "order-1"
+	require.NoError(t, Run(replayed, handler, nil))
+	assert.Equal(t, OutcomeBPMNError, replayed.Outcome())
+	require.Len(t, client.bpmnErrors, 1)
+	assert.Equal(t, "declined", *client.bpmnErrors[0].ErrorCode)
+}
+
+func TestMemoryDedupStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	store := NewMemoryDedupStore(2)
+	require.NoError(t, store.Put(&DedupRecord{Key: "a"}))
+	require.NoError(t, store.Put(&DedupRecord{Key: "b"}))
+	record, err := store.Get("a")
+	require.NoError(t, err)
+	assert.NotNil(t, record)
+
+	require.NoError(t, store.Put(&DedupRecord{Key: "c"}))
+	record, _ = store.Get("b")
+	assert.Nil(t, record)
+	record, _ = store.Get("a")
+	assert.NotNil(t, record)
+}
+
+func TestFileDedupStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileDedupStore(dir, time.Hour)
+	require.NoError(t, err)
+	record, err := store.Get("order/1")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+
+	require.NoError(t, store.Put(&DedupRecord{Key: "order/1", Type: ReportEscalation, Escalation: &QueryHandleEscalation{EscalationCode: strPtr("slow")}}))
+	record, err = store.Get("order/1")
+	require.NoError(t, err)
+	assert.Equal(t, ReportEscalation, record.Type)
+	assert.Equal(t, "slow", *record.Escalation.EscalationCode)
+
+	// records are expired by the time of the last write
+	expired := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(store.path("order/1"), expired, expired))
+	record, err = store.Get("order/1")
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	_, err = os.Stat(store.path("order/1"))
+	assert.True(t, os.IsNotExist(err), "the expired record is deleted")
+
+	require.NoError(t, store.Put(&DedupRecord{Key: "old"}))
+	require.NoError(t, os.Chtimes(store.path("old"), expired, expired))
+	store.prunedAt = expired
+	require.NoError(t, store.Put(&DedupRecord{Key: "new"}))
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1, "expired records are pruned")
+	assert.Equal(t, "new.json", files[0].Name())
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"fmt"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// BPMNError a business error which a handler returns to report a BPMN error instead of a failure
+type BPMNError struct {
+	// Code identifies the BPMN error handler
+	Code string
+	// Message describes the error
+	Message string
+	// Variables are passed to the execution
+	Variables map[string]camundaclientgo.Variable
+}
+
+// NewBPMNError returns a BPMN error with the code and the message
+func NewBPMNError(code, message string) *BPMNError {
+	return &BPMNError{Code: code, Message: message}
+}
+
+// Error returns the message of the error
+func (e *BPMNError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("BPMN error %s", e.Code)
+	}
+
+	return fmt.Sprintf("BPMN error %s: %s", e.Code, e.Message)
+}
+
+func (e *BPMNError) query() QueryHandleBPMNError {
+	query := QueryHandleBPMNError{ErrorCode: &e.Code}
+	if e.Message != "" {
+		query.ErrorMessage = &e.Message
+	}
+	if e.Variables != nil {
+		query.Variables = &e.Variables
+	}
+
+	return query
+}
+
+// Escalation an escalation which a handler returns to report it instead of a failure
+type Escalation struct {
+	// Code identifies the escalation handler
+	Code string
+	// Variables are passed to the execution
+	Variables map[string]camundaclientgo.Variable
+}
+
+// NewEscalation returns an escalation with the code
+func NewEscalation(code string) *Escalation {
+	return &Escalation{Code: code}
+}
+
+// Error returns the message of the escalation
+func (e *Escalation) Error() string {
+	return fmt.Sprintf("escalation %s", e.Code)
+}
+
+func (e *Escalation) query() QueryHandleEscalation {
+	query := QueryHandleEscalation{EscalationCode: &e.Code}
+	if e.Variables != nil {
+		query.Variables = &e.Variables
+	}
+
+	return query
+}
@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestReturnBPMNError(t *testing.T) {
+	client := &fakeClient{}
+	ctx := newTestContext(client)
+
+	err := Run(ctx, func(*Context) error {
+		return fmt.Errorf("charge: %w", &BPMNError{
+			Code:      "payment-declined",
+			Message:   "card expired",
+			Variables: map[string]camundaclientgo.Variable{"reason": {Value: "expired", Type: "String"}},
+		})
+	}, &RetryPolicy{MaxRetries: 3})
+
+	assert.EqualError(t, err, "charge: BPMN error payment-declined: card expired")
+	assert.Equal(t, OutcomeBPMNError, ctx.Outcome())
+	assert.Empty(t, client.failures)
+	require.Len(t, client.bpmnErrors, 1)
+	assert.Equal(t, "payment-declined", *client.bpmnErrors[0].ErrorCode)
+	assert.Equal(t, "card expired", *client.bpmnErrors[0].ErrorMessage)
+	assert.Equal(t, "worker", *client.bpmnErrors[0].WorkerId)
+	assert.Equal(t, "expired", (*client.bpmnErrors[0].Variables)["reason"].Value)
+}
+
+func TestReturnEscalation(t *testing.T) {
+	client := &fakeClient{}
+	ctx := newTestContext(client)
+
+	err := Run(ctx, func(*Context) error {
+		return NewEscalation("manual-review")
+	}, nil)
+
+	assert.EqualError(t, err, "escalation manual-review")
+	assert.Equal(t, OutcomeEscalation, ctx.Outcome())
+	assert.Empty(t, client.failures)
+	require.Len(t, client.escalations, 1)
+	assert.Equal(t, "manual-review", *client.escalations[0].EscalationCode)
+	assert.Nil(t, client.escalations[0].Variables)
+}
@@ -0,0 +1,557 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// DefaultDispatchTimeout the default maximum time a fetched task waits for a free worker
+const DefaultDispatchTimeout = 10 * time.Second
+
+// fetchGroup topics of handlers fetched by the same fetch loops, a group is created for every combination of
+// UsePriority and long polling timeout of handlers
+type fetchGroup struct {
+	usePriority          *bool
+	asyncResponseTimeout *int
+	topics               []*camundaclientgo.QueryFetchAndLockTopic
+}
+
+// fetchGroup returns the fetch group of handlers with the options, p.mu must be held.
+// It returns true if the group is created
+func (p *Processor) fetchGroup(options HandlerOptions) (*fetchGroup, bool) {
+	usePriority := p.options.UsePriority
+	if options.UsePriority != nil {
+		usePriority = options.UsePriority
+	}
+
+	var asyncResponseTimeout *int
+	if options.LongPollingTimeout > 0 {
+		msValue := int(options.LongPollingTimeout / time.Millisecond)
+		asyncResponseTimeout = &msValue
+	} else if p.options.AsyncResponseTimeout != nil {
+		asyncResponseTimeout = p.options.AsyncResponseTimeout
+	} else if p.options.LongPollingTimeout.Nanoseconds() > 0 {
+		msValue := int(p.options.LongPollingTimeout.Nanoseconds() / int64(time.Millisecond))
+		asyncResponseTimeout = &msValue
+	}
+
+	for _, group := range p.groups {
+		if equalBool(group.usePriority, usePriority) && equalInt(group.asyncResponseTimeout, asyncResponseTimeout) {
+			return group, false
+		}
+	}
+
+	group := &fetchGroup{usePriority: usePriority, asyncResponseTimeout: asyncResponseTimeout}
+	p.groups = append(p.groups, group)
+
+	return group, true
+}
+
+// runFetchLoop requests tasks of topics of the group with free workers and dispatches them to worker pools
+// of their handlers. It waits for a free worker if all handlers are busy
+func (p *Processor) runFetchLoop(group *fetchGroup) {
+	retries := 0
+	for {
+		query, topicNames, shares, changed := p.fetchQuery(group)
+		if p.ctx.Err() != nil {
+			p.releaseShares(shares)
+			return
+		}
+		if len(topicNames) == 0 {
+			<-changed
+			continue
+		}
+
+		tasks, err := p.fetchAndLock(query, topicNames, group, shares, changed)
+		if err != nil && p.ctx.Err() != nil {
+			p.releaseShares(shares)
+			return
+		}
+		if errors.Is(err, errRefetch) {
+			p.releaseShares(shares)
+			continue
+		}
+		if err != nil {
+			p.releaseShares(shares)
+			if retries < 60 {
+				retries += 1
+			}
+			p.logger.Error("failed pull", "topics", topicNames, "workerId", query.WorkerId, "error", err, "sleeping", fmt.Sprintf("%d seconds", retries))
+			backoff := time.Duration(retries) * time.Second
+			p.fetchFinished(topicNames, err, backoff)
+			for _, topic := range topicNames {
+				p.metrics.BackoffChanged(topic, backoff)
+			}
+			select {
+			case <-time.After(backoff):
+			case <-p.ctx.Done():
+			}
+			continue
+		}
+		p.fetchFinished(topicNames, nil, 0)
+		if retries > 0 {
+			for _, topic := range topicNames {
+				p.metrics.BackoffChanged(topic, 0)
+			}
+		}
+		retries = 0
+
+		for _, task := range tasks {
+			p.dispatch(task, shares)
+		}
+		p.releaseShares(shares)
+	}
+}
+
+// fetchQuery returns a fetch-and-lock query of topics of the group with free workers, paused handlers are skipped. Free workers are shared
+// between handlers by weights if MaxParallelTasks or MaxTasks of the processor limit them, MaxTasks of the query
+// is the sum of shares. A handler gets no more than its portion of MaxParallelTasks, see portionsLocked, so fetch loops of
+// other groups get workers too. Shares are reserved, so concurrent fetch loops don't request tasks for the same workers,
+// dispatch consumes them and releaseShares releases the rest. It returns shares of pools and a channel closed
+// on a change of free workers too
+func (p *Processor) fetchQuery(group *fetchGroup) (camundaclientgo.QueryFetchAndLock, []string, map[*handlerPool]int, <-chan struct{}) {
+	p.mu.Lock()
+	changed := p.changed
+	portions := p.portionsLocked()
+	var pools []*handlerPool
+	var limits, weights []int
+	seen := map[*handlerPool]bool{}
+	for _, topic := range group.topics {
+		pool := p.pools[topic.TopicName]
+		if seen[pool] {
+			continue
+		}
+		seen[pool] = true
+		if pool.paused {
+			continue
+		}
+
+		free := pool.freeWorkers(portions)
+		if pool.maxTasks > 0 && pool.maxTasks < free {
+			free = pool.maxTasks
+		}
+		pools = append(pools, pool)
+		limits = append(limits, free)
+		weights = append(weights, pool.weight)
+	}
+
+	budget := p.budget()
+	if p.options.MaxTasks > 0 && (budget < 0 || p.options.MaxTasks < budget) {
+		budget = p.options.MaxTasks
+	}
+
+	shares := map[*handlerPool]int{}
+	maxTasks := 0
+	for i, share := range weightedShares(limits, weights, budget) {
+		if share > 0 {
+			shares[pools[i]] = share
+			maxTasks += share
+		}
+	}
+	p.reserve(shares)
+
+	var topics []*camundaclientgo.QueryFetchAndLockTopic
+	var topicNames []string
+	for _, topic := range group.topics {
+		if shares[p.pools[topic.TopicName]] > 0 {
+			topics = append(topics, topic)
+			topicNames = append(topicNames, topic.TopicName)
+		}
+	}
+	p.mu.Unlock()
+
+	return camundaclientgo.QueryFetchAndLock{
+		WorkerId:             p.options.WorkerId,
+		MaxTasks:             maxTasks,
+		UsePriority:          group.usePriority,
+		AsyncResponseTimeout: group.asyncResponseTimeout,
+		Topics:               topics,
+	}, topicNames, shares, changed
+}
+
+// budget returns the number of free workers of the processor limited by MaxParallelTasks, -1 if it isn't limited
+func (p *Processor) budget() int {
+	if p.options.MaxParallelTasks <= 0 {
+		return -1
+	}
+
+	budget := p.options.MaxParallelTasks - int(atomic.LoadInt32(&p.reserved))
+	if budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+// portionsLocked shares MaxParallelTasks between active handlers of all fetch groups by weights and capacities,
+// it returns nil if MaxParallelTasks isn't set. p.mu must be held
+func (p *Processor) portionsLocked() map[*handlerPool]int {
+	if p.options.MaxParallelTasks <= 0 {
+		return nil
+	}
+
+	topics := make([]string, 0, len(p.pools))
+	for topic := range p.pools {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	var pools []*handlerPool
+	var limits, weights []int
+	seen := map[*handlerPool]bool{}
+	for _, topic := range topics {
+		pool := p.pools[topic]
+		if seen[pool] || pool.paused || pool.removed {
+			continue
+		}
+		seen[pool] = true
+		pools = append(pools, pool)
+		limits = append(limits, pool.capacity)
+		weights = append(weights, pool.weight)
+	}
+
+	portions := make(map[*handlerPool]int, len(pools))
+	for i, portion := range weightedShares(limits, weights, p.options.MaxParallelTasks) {
+		portions[pools[i]] = portion
+	}
+	return portions
+}
+
+// freeWorkers returns the number of free workers of the pool limited by its portion of MaxParallelTasks
+func (pool *handlerPool) freeWorkers(portions map[*handlerPool]int) int {
+	free := pool.freeSlots()
+	if portions == nil {
+		return free
+	}
+	if portion := portions[pool] - int(atomic.LoadInt32(&pool.reserved)); portion < free {
+		free = portion
+	}
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// reserve reserves workers of the shares in their pools and in the processor
+func (p *Processor) reserve(shares map[*handlerPool]int) {
+	for pool, share := range shares {
+		atomic.AddInt32(&pool.reserved, int32(share))
+		atomic.AddInt32(&p.reserved, int32(share))
+	}
+}
+
+// releaseShares releases workers of the shares which are not consumed by dispatch,
+// it wakes up waiting fetch loops
+func (p *Processor) releaseShares(shares map[*handlerPool]int) {
+	released := int32(0)
+	for pool, share := range shares {
+		if share > 0 {
+			atomic.AddInt32(&pool.reserved, -int32(share))
+			released += int32(share)
+			shares[pool] = 0
+		}
+	}
+	if released == 0 {
+		return
+	}
+	atomic.AddInt32(&p.reserved, -released)
+
+	p.mu.Lock()
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// refetchNeeded returns true if a pending request of the shares should be cancelled and issued again, because
+// a handler of the group which is not requested got a free worker, or a requested handler is paused or removed.
+// It returns a channel closed on the next change too
+func (p *Processor) refetchNeeded(group *fetchGroup, shares map[*handlerPool]int) (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for pool := range shares {
+		if pool.paused || pool.removed {
+			return true, p.changed
+		}
+	}
+	if p.budget() == 0 {
+		return false, p.changed
+	}
+	portions := p.portionsLocked()
+	for _, topic := range group.topics {
+		pool := p.pools[topic.TopicName]
+		if _, requested := shares[pool]; !requested && !pool.paused && pool.freeWorkers(portions) > 0 {
+			return true, p.changed
+		}
+	}
+
+	return false, p.changed
+}
+
+// weightedShares shares the budget between consumers by weights, a share doesn't exceed the limit of the consumer.
+// Shares left by limited consumers are shared between others, a negative budget is no limit
+func weightedShares(limits, weights []int, budget int) []int {
+	shares := make([]int, len(limits))
+	if budget < 0 {
+		return append(shares[:0], limits...)
+	}
+
+	for budget > 0 {
+		totalWeight := 0
+		for i := range limits {
+			if shares[i] < limits[i] {
+				totalWeight += weights[i]
+			}
+		}
+		if totalWeight == 0 {
+			break
+		}
+
+		used := 0
+		for i := range limits {
+			if shares[i] < limits[i] {
+				share := budget * weights[i] / totalWeight
+				if share > limits[i]-shares[i] {
+					share = limits[i] - shares[i]
+				}
+				shares[i] += share
+				used += share
+			}
+		}
+		if used == 0 {
+			// the budget is too small to share it by weights, the heaviest consumers get a unit
+			var order []int
+			for i := range limits {
+				if shares[i] < limits[i] {
+					order = append(order, i)
+				}
+			}
+			sort.SliceStable(order, func(a, b int) bool { return weights[order[a]] > weights[order[b]] })
+			for _, i := range order[:budget] {
+				shares[i]++
+				used++
+			}
+		}
+		budget -= used
+	}
+
+	return shares
+}
+
+// errRefetch a pending fetch-and-lock request is cancelled to issue it again with free workers
+var errRefetch = errors.New("fetch is cancelled to fetch tasks of free workers")
+
+// refetchDelay the minimum age of a fetch-and-lock request cancelled by a refetch. The engine responds at once
+// if tasks are available, so an older request is long polling and is unlikely to have locked tasks. Tasks locked
+// by a cancelled request are not received and wait for the lock expiration
+const refetchDelay = 500 * time.Millisecond
+
+// fetchAndLock requests tasks of the query. The request is cancelled and errRefetch is returned if the request
+// should be issued again after a change of free workers of the group, see refetchNeeded and refetchDelay
+func (p *Processor) fetchAndLock(query camundaclientgo.QueryFetchAndLock, topicNames []string, group *fetchGroup, shares map[*handlerPool]int, changed <-chan struct{}) ([]*camundaclientgo.ResLockedExternalTask, error) {
+	sentAt := time.Now()
+	fetchCtx, cancelFetch := context.WithCancel(p.ctx)
+	defer cancelFetch()
+	refetch := make(chan struct{})
+	done, stopped := make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+			var needed bool
+			if needed, changed = p.refetchNeeded(group, shares); !needed {
+				continue
+			}
+			if wait := refetchDelay - time.Since(sentAt); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-done:
+					timer.Stop()
+					return
+				}
+				if needed, changed = p.refetchNeeded(group, shares); !needed {
+					continue
+				}
+			}
+			close(refetch)
+			cancelFetch()
+			return
+		}
+	}()
+
+	ctx, span := p.tracer.Start(fetchCtx, "ExternalTask fetchAndLock", camundaclientgo.SpanKindClient)
+	defer span.End()
+	span.SetAttributes(
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeWorkerId, Value: query.WorkerId},
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeTopicNames, Value: topicNames},
+	)
+
+	startedAt := time.Now()
+	event := FetchEvent{WorkerId: query.WorkerId, Topics: topicNames, MaxTasks: query.MaxTasks, StartedAt: startedAt}
+	p.options.Hooks.fetchStarted(event)
+	tasks, err := p.client.WithContext(ctx).ExternalTask.FetchAndLock(query)
+	duration := time.Since(startedAt)
+	event.Duration = duration
+	if err != nil && p.ctx.Err() == nil && isClosed(refetch) {
+		// the request is issued again, tasks locked by the cancelled request wait for the lock expiration
+		event.Err = errRefetch
+		p.options.Hooks.fetchFinished(event)
+		p.logger.Debug("fetch is cancelled to fetch tasks of free workers", "topics", topicNames, "workerId", query.WorkerId)
+		return nil, errRefetch
+	}
+	if err != nil {
+		span.RecordError(err)
+		for _, topic := range topicNames {
+			p.metrics.FetchFinished(topic, 0, duration, err)
+		}
+		event.Err = err
+		p.options.Hooks.fetchFinished(event)
+		return nil, err
+	}
+	span.SetAttributes(camundaclientgo.Attribute{Key: camundaclientgo.AttributeTasksCount, Value: len(tasks)})
+
+	counts := make(map[string]int, len(topicNames))
+	for _, task := range tasks {
+		counts[task.TopicName]++
+	}
+	for _, topic := range topicNames {
+		p.metrics.FetchFinished(topic, counts[topic], duration, nil)
+	}
+	event.Counts = counts
+	p.options.Hooks.fetchFinished(event)
+	p.logger.Debug("tasks fetched", "topics", topicNames, "workerId", query.WorkerId, "count", len(tasks), "duration", duration)
+
+	return tasks, nil
+}
+
+// dispatch sends the task to a reserved worker of the share of its handler in background.
+// A task of an unknown topic, a paused or removed handler or a task exceeding the share of its handler is unlocked,
+// all tasks are unlocked after Shutdown. Workers of unlocked tasks stay reserved until releaseShares
+func (p *Processor) dispatch(task *camundaclientgo.ResLockedExternalTask, shares map[*handlerPool]int) {
+	p.mu.Lock()
+	pool, ok := p.pools[task.TopicName]
+	log, reason := p.logger.Warn, ""
+	switch {
+	case p.draining:
+		reason = "processor is shut down"
+	case !ok:
+		reason = "no handler of the topic"
+	case pool.paused:
+		reason = "handler is paused"
+	case shares[pool] <= 0:
+		// it is expected if handlers share workers
+		log, reason = p.logger.Debug, "task exceeds the share of the handler"
+	}
+	if reason != "" {
+		p.mu.Unlock()
+		log(reason+", the task is unlocked", taskLogFields(task)...)
+		p.unlock(task)
+		return
+	}
+	// the worker is reserved by fetchQuery, it is released after the task is handled
+	shares[pool]--
+	p.mu.Unlock()
+
+	p.options.Hooks.taskDispatched(TaskEvent{Task: task, Time: time.Now()})
+	go p.send(task, pool)
+}
+
+// send sends the task to a worker of the pool, the task is unlocked if no worker is free within the dispatch timeout.
+// It happens if more tasks are fetched than the pool or the processor has free workers, e.g. by several fetch loops.
+// The task is unlocked if the pool is stopped before too
+func (p *Processor) send(task *camundaclientgo.ResLockedExternalTask, pool *handlerPool) {
+	timeout := p.options.DispatchTimeout
+	if timeout <= 0 {
+		timeout = DefaultDispatchTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-timer.C:
+			p.dispatchTimedOut(task, pool, timeout, false)
+			return
+		case <-pool.stopped:
+			p.dispatchStopped(task, pool, false)
+			return
+		}
+	}
+
+	select {
+	case pool.tasks <- task:
+	case <-timer.C:
+		p.dispatchTimedOut(task, pool, timeout, true)
+	case <-pool.stopped:
+		p.dispatchStopped(task, pool, true)
+	}
+}
+
+func (p *Processor) dispatchTimedOut(task *camundaclientgo.ResLockedExternalTask, pool *handlerPool, timeout time.Duration, holdsSlot bool) {
+	p.logger.Warn("no free worker for the task, the task is unlocked", append(taskLogFields(task), "timeout", timeout)...)
+	p.unlock(task)
+	p.release(pool, holdsSlot)
+}
+
+func (p *Processor) dispatchStopped(task *camundaclientgo.ResLockedExternalTask, pool *handlerPool, holdsSlot bool) {
+	p.logger.Debug("handler is stopped, the task is unlocked", taskLogFields(task)...)
+	p.unlock(task)
+	p.release(pool, holdsSlot)
+}
+
+// release frees a reserved worker of the pool and a slot of the processor if it is held,
+// it wakes up waiting fetch loops
+func (p *Processor) release(pool *handlerPool, holdsSlot bool) {
+	if holdsSlot && p.slots != nil {
+		<-p.slots
+	}
+	atomic.AddInt32(&pool.reserved, -1)
+	atomic.AddInt32(&p.reserved, -1)
+
+	p.mu.Lock()
+	p.notifyLocked()
+	p.mu.Unlock()
+}
+
+// notifyLocked wakes up fetch loops waiting for a change, p.mu must be held
+func (p *Processor) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *Processor) unlock(task *camundaclientgo.ResLockedExternalTask) {
+	if err := p.client.ExternalTask.Unlock(task.Id); err != nil {
+		p.logger.Error("error unlock task", append(taskLogFields(task), "error", err)...)
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func equalBool(a, b *bool) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
+
+func equalInt(a, b *int) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
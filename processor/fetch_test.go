@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func newTestProcessor(t *testing.T, options *Options, handler http.HandlerFunc) *Processor {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := camundaclientgo.NewClient(camundaclientgo.ClientOptions{EndpointUrl: server.URL})
+	return NewProcessor(client, options, nil)
+}
+
+func TestFetchQueryFreeSlots(t *testing.T) {
+	p := newTestProcessor(t, &Options{MaxTasks: 3}, http.NotFound)
+	busy := &handlerPool{capacity: 2, reserved: 2, weight: 1}
+	free := &handlerPool{capacity: 5, reserved: 1, weight: 1}
+	p.pools = map[string]*handlerPool{"busy": busy, "free-a": free, "free-b": free}
+	group := &fetchGroup{topics: []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "busy"}, {TopicName: "free-a"}, {TopicName: "free-b"}}}
+
+	query, topicNames, shares, _ := p.fetchQuery(group)
+	assert.Equal(t, []string{"free-a", "free-b"}, topicNames)
+	assert.Equal(t, 3, query.MaxTasks)
+	assert.Equal(t, map[*handlerPool]int{free: 3}, shares)
+	assert.Equal(t, int32(4), free.reserved, "shares are reserved")
+	assert.Equal(t, int32(3), p.reserved)
+
+	query, _, _, _ = p.fetchQuery(group)
+	assert.Equal(t, 1, query.MaxTasks, "another fetch loop gets the rest")
+	free.reserved, p.reserved = 1, 0
+
+	p.options.MaxTasks = 10
+	query, _, shares, _ = p.fetchQuery(group)
+	assert.Equal(t, 4, query.MaxTasks)
+	p.releaseShares(shares)
+	assert.Equal(t, int32(1), free.reserved)
+	assert.Equal(t, int32(0), p.reserved)
+
+	free.reserved = 5
+	_, topicNames, _, changed := p.fetchQuery(group)
+	assert.Empty(t, topicNames)
+
+	p.reserved = 1
+	p.release(free, false)
+	select {
+	case <-changed:
+	default:
+		t.Fatal("release does not wake up fetch loops")
+	}
+	_, topicNames, _, _ = p.fetchQuery(group)
+	assert.Equal(t, []string{"free-a", "free-b"}, topicNames)
+}
+
+func TestDispatchTimeout(t *testing.T) {
+	var unlocked int32
+	p := newTestProcessor(t, &Options{DispatchTimeout: 10 * time.Millisecond}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/external-task/task/unlock") {
+			atomic.AddInt32(&unlocked, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	pool := &handlerPool{capacity: 1, tasks: make(chan *camundaclientgo.ResLockedExternalTask)}
+	p.pools["topic"] = pool
+
+	shares := map[*handlerPool]int{pool: 1}
+	p.reserve(shares)
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "task", TopicName: "topic"}, shares)
+	assert.Equal(t, 0, pool.freeSlots())
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&unlocked) == 1 && pool.freeSlots() == 1
+	}, time.Second, time.Millisecond)
+
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "task", TopicName: "unknown"}, nil)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&unlocked))
+
+	// the task exceeds the share of the pool
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "task", TopicName: "topic"}, map[*handlerPool]int{pool: 0})
+	assert.Equal(t, int32(3), atomic.LoadInt32(&unlocked))
+	assert.Equal(t, 1, pool.freeSlots())
+}
+
+func TestFetchQueryGlobalLimit(t *testing.T) {
+	p := newTestProcessor(t, &Options{MaxParallelTasks: 10}, http.NotFound)
+	flooded := &handlerPool{capacity: 200, weight: 1}
+	images := &handlerPool{capacity: 2, weight: 1}
+	mails := &handlerPool{capacity: 200, weight: 3}
+	p.pools = map[string]*handlerPool{"flooded": flooded, "images": images, "mails": mails}
+	group := &fetchGroup{topics: []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "flooded"}, {TopicName: "images"}, {TopicName: "mails"}}}
+
+	query, _, shares, _ := p.fetchQuery(group)
+	assert.Equal(t, 10, query.MaxTasks)
+	assert.Equal(t, map[*handlerPool]int{flooded: 2, images: 2, mails: 6}, shares)
+	p.releaseShares(shares)
+
+	p.reserved = 9
+	query, topicNames, shares, _ := p.fetchQuery(group)
+	assert.Equal(t, 1, query.MaxTasks)
+	assert.Equal(t, []string{"mails"}, topicNames)
+	assert.Equal(t, map[*handlerPool]int{mails: 1}, shares)
+}
+
+func TestFetchQueryGroupsShareGlobalLimit(t *testing.T) {
+	p := newTestProcessor(t, &Options{MaxParallelTasks: 50}, http.NotFound)
+	images := &handlerPool{capacity: 2, weight: 1}
+	mails := &handlerPool{capacity: 200, weight: 3}
+	p.pools = map[string]*handlerPool{"images": images, "mails": mails}
+	imageGroup := &fetchGroup{topics: []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "images"}}}
+	mailGroup := &fetchGroup{topics: []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "mails"}}}
+
+	query, _, _, _ := p.fetchQuery(mailGroup)
+	assert.Equal(t, 48, query.MaxTasks, "workers of images are left to their fetch loop")
+	query, _, _, _ = p.fetchQuery(imageGroup)
+	assert.Equal(t, 2, query.MaxTasks)
+	assert.Equal(t, int32(50), p.reserved)
+}
+
+func TestWeightedShares(t *testing.T) {
+	tests := []struct {
+		limits  []int
+		weights []int
+		budget  int
+		shares  []int
+	}{
+		{[]int{5, 5}, []int{1, 1}, -1, []int{5, 5}},
+		{[]int{5, 5}, []int{1, 1}, 4, []int{2, 2}},
+		{[]int{5, 5}, []int{1, 3}, 4, []int{1, 3}},
+		{[]int{1, 10}, []int{1, 1}, 6, []int{1, 5}},
+		{[]int{10, 10, 10}, []int{1, 1, 2}, 2, []int{0, 0, 2}},
+		{[]int{10, 10, 10}, []int{1, 1, 1}, 2, []int{1, 1, 0}},
+		{[]int{0, 3}, []int{5, 1}, 10, []int{0, 3}},
+		{[]int{}, []int{}, 10, []int{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.shares, weightedShares(test.limits, test.weights, test.budget), "%v", test)
+	}
+}
@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultHealthFetchErrors the default number of consecutive failed fetch-and-lock requests of a topic
+// which make the processor unhealthy
+const DefaultHealthFetchErrors = 3
+
+// Health a snapshot of the state of the processor
+type Health struct {
+	// Draining the processor is shut down and waits for in-flight tasks
+	Draining bool `json:"draining"`
+	// MaxFetchErrors the number of consecutive failed fetch-and-lock requests of a topic which make
+	// the processor unhealthy, see Options.HealthFetchErrors
+	MaxFetchErrors int `json:"maxFetchErrors"`
+	// Topics states of topics in the order of registration
+	Topics []TopicHealth `json:"topics"`
+}
+
+// TopicHealth a state of a topic
+type TopicHealth struct {
+	Topic string `json:"topic"`
+	// LastFetch the time of the last successful fetch-and-lock request of the topic, zero if it isn't fetched yet
+	LastFetch time.Time `json:"lastFetch"`
+	// FetchErrors the number of consecutive failed fetch-and-lock requests
+	FetchErrors int `json:"fetchErrors"`
+	// Backoff the current delay before the next fetch-and-lock request after an error, it is encoded as
+	// a duration string, e.g. "3s"
+	Backoff time.Duration `json:"backoff"`
+	// InFlight the number of tasks handled by the handler of the topic
+	InFlight int `json:"inFlight"`
+	// Capacity the maximum number of parallel tasks of the handler of the topic
+	Capacity int  `json:"capacity"`
+	Paused   bool `json:"paused"`
+}
+
+// MarshalJSON marshal to json
+func (t TopicHealth) MarshalJSON() ([]byte, error) {
+	type Alias TopicHealth
+
+	return json.Marshal(&struct {
+		*Alias
+
+		Backoff string `json:"backoff"`
+	}{
+		Alias:   (*Alias)(&t),
+		Backoff: t.Backoff.String(),
+	})
+}
+
+// UnmarshalJSON unmarshal from json
+func (t *TopicHealth) UnmarshalJSON(data []byte) error {
+	type Alias TopicHealth
+
+	value := &struct {
+		*Alias
+
+		Backoff string `json:"backoff"`
+	}{
+		Alias: (*Alias)(t),
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return err
+	}
+
+	t.Backoff = 0
+	if value.Backoff != "" {
+		backoff, err := time.ParseDuration(value.Backoff)
+		if err != nil {
+			return fmt.Errorf("invalid backoff: %w", err)
+		}
+		t.Backoff = backoff
+	}
+
+	return nil
+}
+
+// Healthy returns true if the processor isn't draining and no active topic has MaxFetchErrors consecutive
+// failed fetches (default: DefaultHealthFetchErrors)
+func (h Health) Healthy() bool {
+	if h.Draining {
+		return false
+	}
+	maxFetchErrors := h.MaxFetchErrors
+	if maxFetchErrors <= 0 {
+		maxFetchErrors = DefaultHealthFetchErrors
+	}
+	for _, topic := range h.Topics {
+		if !topic.Paused && topic.FetchErrors >= maxFetchErrors {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchState a fetch state of a topic
+type fetchState struct {
+	lastFetch   time.Time
+	fetchErrors int
+	backoff     time.Duration
+}
+
+// fetchFinished updates fetch states of the topics by the result of a fetch-and-lock request
+func (p *Processor) fetchFinished(topicNames []string, err error, backoff time.Duration) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, topic := range topicNames {
+		state, ok := p.fetchStates[topic]
+		if !ok {
+			state = &fetchState{}
+			p.fetchStates[topic] = state
+		}
+
+		if err != nil {
+			state.fetchErrors++
+			state.backoff = backoff
+		} else {
+			state.lastFetch = now
+			state.fetchErrors = 0
+			state.backoff = 0
+		}
+	}
+}
+
+// Health returns a snapshot of the state of the processor and its topics
+func (p *Processor) Health() Health {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := Health{Draining: p.draining, MaxFetchErrors: p.options.HealthFetchErrors, Topics: []TopicHealth{}}
+	if health.MaxFetchErrors <= 0 {
+		health.MaxFetchErrors = DefaultHealthFetchErrors
+	}
+	for _, group := range p.groups {
+		for _, topic := range group.topics {
+			pool := p.pools[topic.TopicName]
+			topicHealth := TopicHealth{
+				Topic:    topic.TopicName,
+				InFlight: int(atomic.LoadInt32(&pool.inFlight)),
+				Capacity: pool.capacity,
+				Paused:   pool.paused,
+			}
+			if state, ok := p.fetchStates[topic.TopicName]; ok {
+				topicHealth.LastFetch = state.lastFetch
+				topicHealth.FetchErrors = state.fetchErrors
+				topicHealth.Backoff = state.backoff
+			}
+			health.Topics = append(health.Topics, topicHealth)
+		}
+	}
+
+	return health
+}
+
+// HealthHandler returns a http handler of probes, it responds with the health of the processor as JSON.
+// The status code is 200 if the processor is healthy, otherwise 503, see Health.Healthy
+func (p *Processor) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := p.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+		if health.Healthy() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			p.logger.Error("error write health", "error", err)
+		}
+	})
+}
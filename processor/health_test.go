@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestHealth(t *testing.T) {
+	p := newTestProcessor(t, &Options{HealthFetchErrors: 2}, http.NotFound)
+	images := &handlerPool{capacity: 2, inFlight: 1, tasks: make(chan *camundaclientgo.ResLockedExternalTask), stopped: make(chan struct{})}
+	mails := &handlerPool{capacity: 10, paused: true, tasks: make(chan *camundaclientgo.ResLockedExternalTask), stopped: make(chan struct{})}
+	p.pools = map[string]*handlerPool{"images": images, "mails": mails}
+	p.groups = []*fetchGroup{{topics: []*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "images"}, {TopicName: "mails"}}}}
+
+	p.fetchFinished([]string{"images", "mails"}, nil, 0)
+	p.fetchFinished([]string{"images"}, errors.New("connection refused"), 2*time.Second)
+	assert.True(t, p.Health().Healthy(), "a single failed fetch is tolerated")
+	p.fetchFinished([]string{"images"}, errors.New("connection refused"), 3*time.Second)
+
+	health := p.Health()
+	assert.False(t, health.Healthy())
+	assert.Equal(t, 2, health.MaxFetchErrors)
+	require.Len(t, health.Topics, 2)
+	assert.Equal(t, "images", health.Topics[0].Topic)
+	assert.Equal(t, 2, health.Topics[0].FetchErrors)
+	assert.Equal(t, 3*time.Second, health.Topics[0].Backoff)
+	assert.Equal(t, 1, health.Topics[0].InFlight)
+	assert.Equal(t, 2, health.Topics[0].Capacity)
+	assert.WithinDuration(t, time.Now(), health.Topics[1].LastFetch, time.Second)
+	assert.True(t, health.Topics[1].Paused)
+
+	recorder := httptest.NewRecorder()
+	p.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"backoff":"3s"`)
+	var body Health
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.MaxFetchErrors)
+	assert.Equal(t, 2, body.Topics[0].FetchErrors)
+	assert.Equal(t, 3*time.Second, body.Topics[0].Backoff)
+
+	p.fetchFinished([]string{"images"}, nil, 0)
+	recorder = httptest.NewRecorder()
+	p.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	images.inFlight = 0
+	require.NoError(t, p.Shutdown(context.Background()))
+	assert.True(t, p.Health().Draining)
+	assert.False(t, p.Health().Healthy())
+}
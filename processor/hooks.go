@@ -0,0 +1,134 @@
+package processor
+
+import (
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// FetchEvent an event of a fetch-and-lock request
+type FetchEvent struct {
+	WorkerId string
+	Topics   []string
+	// MaxTasks the maximum number of tasks requested
+	MaxTasks int
+	// Counts numbers of fetched tasks by topic, it is set when the request is finished
+	Counts    map[string]int
+	StartedAt time.Time
+	// Duration the duration of the request, it is set when the request is finished
+	Duration time.Duration
+	Err      error
+}
+
+// Count returns the number of fetched tasks
+func (e FetchEvent) Count() int {
+	count := 0
+	for _, c := range e.Counts {
+		count += c
+	}
+
+	return count
+}
+
+// TaskEvent an event of a task
+type TaskEvent struct {
+	Task *camundaclientgo.ResLockedExternalTask
+	Time time.Time
+	// Duration the duration of the handler, it is set when the handler is finished
+	Duration time.Duration
+	// Outcome the result reported to the engine, it is set when the handler is finished
+	Outcome Outcome
+	// Err the error of the handler or the error of the report of the lock
+	Err error
+	// LockDuration the new lock duration of an extended lock
+	LockDuration time.Duration
+}
+
+// Hooks callbacks of lifecycle events of the processor, nil callbacks are skipped.
+// Callbacks are called synchronously by fetch loops and workers, so they must be fast
+type Hooks struct {
+	// OnFetchStarted is called before a fetch-and-lock request
+	OnFetchStarted func(event FetchEvent)
+	// OnFetchFinished is called after a fetch-and-lock request with counts of fetched tasks or the error
+	OnFetchFinished func(event FetchEvent)
+	// OnTaskDispatched is called when a fetched task is sent to a worker of its handler
+	OnTaskDispatched func(event TaskEvent)
+	// OnHandlerStarted is called before a handler runs
+	OnHandlerStarted func(event TaskEvent)
+	// OnTaskCompleted is called after a handler completed a task
+	OnTaskCompleted func(event TaskEvent)
+	// OnBPMNError is called after a BPMN error of a task is reported
+	OnBPMNError func(event TaskEvent)
+	// OnEscalation is called after an escalation of a task is reported
+	OnEscalation func(event TaskEvent)
+	// OnFailure is called after a failure of a task is reported
+	OnFailure func(event TaskEvent)
+	// OnPanic is called after a panic of a handler is recovered and reported as a failure, Err is a *PanicError
+	OnPanic func(event TaskEvent)
+	// OnLockExtended is called after Context.ExtendLock extended the lock of a task
+	OnLockExtended func(event TaskEvent)
+	// OnLockLost is called once per task if the handler loses the lock of its task: Context.ExtendLock is rejected
+	// by the engine, the handler overruns its deadline or the lock expires before the handler reported a result
+	// (Err is ErrLockExpired). The lock expiration is detected by a timer, the callback runs in its goroutine
+	OnLockLost func(event TaskEvent)
+}
+
+func (h *Hooks) fetchStarted(event FetchEvent) {
+	if h != nil && h.OnFetchStarted != nil {
+		h.OnFetchStarted(event)
+	}
+}
+
+func (h *Hooks) fetchFinished(event FetchEvent) {
+	if h != nil && h.OnFetchFinished != nil {
+		h.OnFetchFinished(event)
+	}
+}
+
+func (h *Hooks) taskDispatched(event TaskEvent) {
+	if h != nil && h.OnTaskDispatched != nil {
+		h.OnTaskDispatched(event)
+	}
+}
+
+func (h *Hooks) handlerStarted(event TaskEvent) {
+	if h != nil && h.OnHandlerStarted != nil {
+		h.OnHandlerStarted(event)
+	}
+}
+
+// taskFinished calls the hook of the outcome of the task
+func (h *Hooks) taskFinished(event TaskEvent) {
+	if h == nil {
+		return
+	}
+
+	var hook func(event TaskEvent)
+	switch event.Outcome {
+	case OutcomeComplete:
+		hook = h.OnTaskCompleted
+	case OutcomeBPMNError:
+		hook = h.OnBPMNError
+	case OutcomeEscalation:
+		hook = h.OnEscalation
+	case OutcomeFailure:
+		hook = h.OnFailure
+	case OutcomePanic:
+		hook = h.OnPanic
+	}
+	if hook != nil {
+		hook(event)
+	}
+}
+
+func (h *Hooks) lockExtended(event TaskEvent) {
+	if h != nil && h.OnLockExtended != nil {
+		h.OnLockExtended(event)
+	}
+}
+
+func (h *Hooks) lockLost(event TaskEvent) {
+	if h != nil && h.OnLockLost != nil {
+		h.OnLockLost(event)
+	}
+}
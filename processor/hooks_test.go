@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// lockClient rejects lock extensions with the error
+type lockClient struct {
+	fakeClient
+	err error
+}
+
+func (c *lockClient) ExtendLock(string, camundaclientgo.QueryExtendLock) error {
+	return c.err
+}
+
+func TestHooksOfOutcomes(t *testing.T) {
+	var called []Outcome
+	hook := func(event TaskEvent) {
+		called = append(called, event.Outcome)
+	}
+	hooks := &Hooks{OnTaskCompleted: hook, OnBPMNError: hook, OnEscalation: hook, OnFailure: hook, OnPanic: hook}
+
+	for _, outcome := range []Outcome{OutcomeComplete, OutcomeBPMNError, OutcomeEscalation, OutcomeFailure, OutcomePanic, OutcomeNone} {
+		hooks.taskFinished(TaskEvent{Outcome: outcome})
+	}
+	assert.Equal(t, []Outcome{OutcomeComplete, OutcomeBPMNError, OutcomeEscalation, OutcomeFailure, OutcomePanic}, called)
+
+	var nilHooks *Hooks
+	nilHooks.taskFinished(TaskEvent{Outcome: OutcomeComplete})
+	(&Hooks{}).fetchStarted(FetchEvent{})
+}
+
+func TestExtendLockHooks(t *testing.T) {
+	var extended, lost []TaskEvent
+	hooks := &Hooks{
+		OnLockExtended: func(event TaskEvent) { extended = append(extended, event) },
+		OnLockLost:     func(event TaskEvent) { lost = append(lost, event) },
+	}
+
+	client := &lockClient{}
+	ctx := newTestContext(client)
+	ctx.hooks = hooks
+	assert.NoError(t, ctx.ExtendLock(time.Minute))
+
+	client.err = errUnavailable
+	assert.Error(t, ctx.ExtendLock(time.Minute))
+
+	client.err = &camundaclientgo.Error{Type: "BadUserRequestException", Message: "lock is expired"}
+	assert.Error(t, ctx.ExtendLock(time.Minute))
+
+	if assert.Len(t, extended, 1) {
+		assert.Equal(t, time.Minute, extended[0].LockDuration)
+	}
+	if assert.Len(t, lost, 1) {
+		assert.EqualError(t, lost[0].Err, "lock is expired")
+	}
+}
+
+func TestLockExpiredHook(t *testing.T) {
+	lost := make(chan TaskEvent, 2)
+	hooks := &Hooks{OnLockLost: func(event TaskEvent) { lost <- event }}
+
+	ctx := newTestContext(&lockClient{})
+	ctx.hooks = hooks
+	ctx.watchLock(time.Now().Add(20 * time.Millisecond))
+	require.NoError(t, ctx.ExtendLock(time.Minute))
+	select {
+	case <-lost:
+		t.Fatal("the extended lock is lost")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ctx.watchLock(time.Now().Add(10 * time.Millisecond))
+	select {
+	case event := <-lost:
+		assert.Equal(t, ErrLockExpired, event.Err)
+	case <-time.After(time.Second):
+		t.Fatal("the expired lock is not reported")
+	}
+	ctx.client = &lockClient{err: &camundaclientgo.Error{Message: "lock is expired"}}
+	assert.Error(t, ctx.ExtendLock(time.Minute))
+	assert.Empty(t, lost, "the lock is lost once")
+
+	// the lock of a reported task or a finished handler doesn't matter
+	completed := newTestContext(&lockClient{})
+	completed.hooks = hooks
+	completed.watchLock(time.Now().Add(10 * time.Millisecond))
+	require.NoError(t, completed.Complete(QueryComplete{}))
+	finished := newTestContext(&lockClient{})
+	finished.hooks = hooks
+	finished.watchLock(time.Now().Add(10 * time.Millisecond))
+	finished.stopLockWatch()
+	select {
+	case <-lost:
+		t.Fatal("the lock is reported lost")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,32 @@
+package processor
+
+import "time"
+
+// Metrics collects metrics of the processor per topic.
+// A Prometheus implementation is in the module github.com/wurenquyu/camunda-client-go/prometheus
+type Metrics interface {
+	// FetchFinished is called for every topic of a fetch-and-lock request with the number of tasks
+	// fetched for the topic. The poll is empty if count is 0 and err is nil
+	FetchFinished(topic string, count int, duration time.Duration, err error)
+	// BackoffChanged is called when the puller of the topic starts or stops (duration is 0) sleeping after a failed fetch
+	BackoffChanged(topic string, duration time.Duration)
+	// TaskStarted is called before the handler runs with the number of running tasks and the maximum parallel tasks of the handler
+	TaskStarted(topic string, inFlight, capacity int)
+	// TaskFinished is called after the handler returns with the outcome of the task
+	TaskFinished(topic string, outcome Outcome, duration time.Duration, inFlight, capacity int)
+}
+
+// NoopMetrics metrics which collect nothing. It is used when no metrics are configured
+type NoopMetrics struct{}
+
+// FetchFinished does nothing
+func (NoopMetrics) FetchFinished(string, int, time.Duration, error) {}
+
+// BackoffChanged does nothing
+func (NoopMetrics) BackoffChanged(string, time.Duration) {}
+
+// TaskStarted does nothing
+func (NoopMetrics) TaskStarted(string, int, int) {}
+
+// TaskFinished does nothing
+func (NoopMetrics) TaskFinished(string, Outcome, time.Duration, int, int) {}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// Middleware wraps a handler, e.g. to recover panics, log or trace tasks
+type Middleware func(next Handler) Handler
+
+// Chain wraps the handler by the middlewares, the first middleware is the outermost one
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// PanicError an error of a recovered panic of a handler, the processor reports it as a failure
+// with the stack trace in the error details
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error returns the error message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("fatal error in task: %v", e.Value)
+}
+
+// Recovery returns a middleware which recovers a panic of the next handler and returns it as PanicError.
+// The processor adds it to every handler unless Options.DisableRecovery is set
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+
+			return next(ctx)
+		}
+	}
+}
+
+// Logging returns a middleware which logs every handled task with the outcome and the duration.
+// Tasks are logged at the info level, tasks with an error at the warn level
+func Logging(logger camundaclientgo.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			startedAt := time.Now()
+			err := next(ctx)
+
+			fields := append(taskLogFields(ctx.Task), "outcome", ctx.Outcome(), "duration", time.Since(startedAt))
+			if err != nil {
+				logger.Warn("task handled with error", append(fields, "error", err)...)
+			} else {
+				logger.Info("task handled", fields...)
+			}
+
+			return err
+		}
+	}
+}
+
+// Timing returns a middleware which passes the duration of every handled task to observe
+func Timing(observe func(ctx *Context, duration time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			startedAt := time.Now()
+			err := next(ctx)
+			observe(ctx, time.Since(startedAt), err)
+
+			return err
+		}
+	}
+}
+
+// Timeout returns a middleware which cancels the context of the next handler after the timeout.
+// If the handler does not return in time, the middleware returns an error wrapping context.DeadlineExceeded
+// and the processor reports a failure. The handler is abandoned: its reports are rejected by ErrAbandoned and
+// its worker is held until it returns
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx.Context(), timeout)
+			defer cancel()
+
+			if abandoned, err := runAbandonable(ctx, timeoutCtx, next); !abandoned {
+				return err
+			}
+
+			return fmt.Errorf("handler did not return within %s: %w", timeout, context.DeadlineExceeded)
+		}
+	}
+}
+
+// runAbandonable runs the next handler in background with runCtx and waits until it returns. If the deadline
+// of runCtx is exceeded before, reports of the handler are rejected and it returns true unless the handler
+// reported a result already. The handler keeps its worker until it returns, see run.
+// If runCtx is cancelled, e.g. by Shutdown, it waits for the handler
+func runAbandonable(ctx *Context, runCtx context.Context, next Handler) (bool, error) {
+	handlerCtx := ctx.WithContext(runCtx)
+	handlerCtx.gate = &reportGate{parent: ctx.gate}
+
+	done := make(chan error, 1)
+	ctx.state.abandoned.Add(1)
+	go func() {
+		defer ctx.state.abandoned.Done()
+		done <- Recovery()(next)(handlerCtx)
+	}()
+
+	select {
+	case err := <-done:
+		return false, err
+	case <-runCtx.Done():
+	}
+	if !errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		return false, <-done
+	}
+
+	handlerCtx.gate.close()
+	select {
+	case err := <-done:
+		return false, err
+	default:
+	}
+	if ctx.Outcome() != OutcomeNone {
+		// the result is reported before the deadline, the handler is abandoned without a failure
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Tracing returns a middleware which runs the next handler in an internal span of the tracer with the name.
+// The span is a child of the span of the processor
+func Tracing(tracer camundaclientgo.Tracer, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) error {
+			spanCtx, span := tracer.Start(ctx.Context(), name, camundaclientgo.SpanKindInternal)
+			defer span.End()
+			span.SetAttributes(
+				camundaclientgo.Attribute{Key: camundaclientgo.AttributeTopicName, Value: ctx.Task.TopicName},
+				camundaclientgo.Attribute{Key: camundaclientgo.AttributeTaskId, Value: ctx.Task.Id},
+			)
+
+			err := next(ctx.WithContext(spanCtx))
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetAttributes(camundaclientgo.Attribute{Key: camundaclientgo.AttributeTaskOutcome, Value: string(ctx.Outcome())})
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// fakeClient records reports of handlers
+type fakeClient struct {
+	bpmnErrors  []camundaclientgo.QueryHandleBPMNError
+	escalations []camundaclientgo.QueryHandleEscalation
+	failures    []camundaclientgo.QueryHandleFailure
+}
+
+func (c *fakeClient) Complete(string, camundaclientgo.QueryComplete) error {
+	return nil
+}
+
+func (c *fakeClient) HandleBPMNError(_ string, query camundaclientgo.QueryHandleBPMNError) error {
+	c.bpmnErrors = append(c.bpmnErrors, query)
+	return nil
+}
+
+func (c *fakeClient) HandleEscalation(_ string, query camundaclientgo.QueryHandleEscalation) error {
+	c.escalations = append(c.escalations, query)
+	return nil
+}
+
+func (c *fakeClient) HandleFailure(_ string, query camundaclientgo.QueryHandleFailure) error {
+	c.failures = append(c.failures, query)
+	return nil
+}
+
+func (c *fakeClient) ExtendLock(string, camundaclientgo.QueryExtendLock) error {
+	return nil
+}
+
+func newTestContext(client ExternalTaskClient) *Context {
+	return NewContext(context.Background(), &camundaclientgo.ResLockedExternalTask{
+		Id:        "task",
+		TopicName: "topic",
+		WorkerId:  "worker",
+	}, client)
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	middleware := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx *Context) error {
+				calls = append(calls, name+" before")
+				err := next(ctx)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+
+	handler := Chain(func(ctx *Context) error {
+		calls = append(calls, "handler")
+		return ctx.Complete(QueryComplete{})
+	}, middleware("first"), middleware("second"))
+
+	ctx := newTestContext(&fakeClient{})
+	require.NoError(t, handler(ctx))
+	assert.Equal(t, []string{"first before", "second before", "handler", "second after", "first after"}, calls)
+	assert.Equal(t, OutcomeComplete, ctx.Outcome())
+}
+
+func TestRecovery(t *testing.T) {
+	client := &fakeClient{}
+	ctx := newTestContext(client)
+
+	err := Run(ctx, func(*Context) error {
+		panic("boom")
+	}, nil)
+
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Equal(t, OutcomePanic, ctx.Outcome())
+	require.Len(t, client.failures, 1)
+	assert.Equal(t, "fatal error in task: boom", *client.failures[0].ErrorMessage)
+	assert.Contains(t, *client.failures[0].ErrorDetails, "Stack trace:")
+}
+
+func TestTimeout(t *testing.T) {
+	client := &fakeClient{}
+	ctx := newTestContext(client)
+
+	done := make(chan struct{})
+	err := Run(ctx, Timeout(10*time.Millisecond)(func(ctx *Context) error {
+		<-ctx.Context().Done()
+		close(done)
+		return ctx.Context().Err()
+	}), nil)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, OutcomeFailure, ctx.Outcome())
+	require.Len(t, client.failures, 1)
+	<-done
+
+	err = Run(newTestContext(client), Timeout(time.Second)(func(ctx *Context) error {
+		_, ok := ctx.Context().Deadline()
+		assert.True(t, ok)
+		return ctx.Complete(QueryComplete{})
+	}), nil)
+	assert.NoError(t, err)
+}
+
+func TestTimeoutAbandonsHandler(t *testing.T) {
+	client := &recordingClient{}
+	ctx := newTestContext(client)
+
+	var lateErr error
+	returned := false
+	err := Run(ctx, Timeout(10*time.Millisecond)(func(ctx *Context) error {
+		<-ctx.Context().Done()
+		// the handler ignores the cancellation and reports later
+		time.Sleep(20 * time.Millisecond)
+		lateErr = ctx.Complete(QueryComplete{})
+		returned = true
+		return nil
+	}), nil)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, returned, "the worker is held until the handler returns")
+	assert.Equal(t, ErrAbandoned, lateErr)
+	assert.Equal(t, OutcomeFailure, ctx.Outcome())
+	assert.Empty(t, client.completes)
+
+	ctx = newTestContext(client)
+	release := make(chan struct{})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		close(release)
+	}()
+	err = Run(ctx, Timeout(10*time.Millisecond)(func(ctx *Context) error {
+		if err := ctx.Complete(QueryComplete{}); err != nil {
+			return err
+		}
+		<-release
+		return nil
+	}), nil)
+	assert.NoError(t, err, "the result is reported before the timeout")
+	assert.Equal(t, OutcomeComplete, ctx.Outcome())
+	assert.Len(t, client.completes, 1)
+}
+
+func TestTimingAndTracing(t *testing.T) {
+	var observed time.Duration
+	var observedErr error
+	handler := Chain(func(ctx *Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("failed")
+	}, Timing(func(_ *Context, duration time.Duration, err error) {
+		observed, observedErr = duration, err
+	}), Tracing(camundaclientgo.NoopTracer{}, "handler"), Logging(camundaclientgo.NopLogger{}))
+
+	err := handler(newTestContext(&fakeClient{}))
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, err, observedErr)
+	assert.GreaterOrEqual(t, int64(observed), int64(5*time.Millisecond))
+}
@@ -0,0 +1,411 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+const (
+	// DefaultOutboxDir the default directory of the file store of the outbox
+	DefaultOutboxDir = "camunda-outbox"
+	// DefaultOutboxMaxBackoff the default maximum delay between delivery attempts of a report
+	DefaultOutboxMaxBackoff = 10 * time.Second
+	// DefaultOutboxExpiration the time to deliver a report of a task without a lock expiration time
+	DefaultOutboxExpiration = time.Minute
+)
+
+// ErrReportPending is wrapped by the error of a report which the engine didn't receive, the report is saved
+// to the outbox and delivered in background. The outcome of the task is set, a handler may return the error
+// without a failure reported
+var ErrReportPending = errors.New("report is pending in the outbox")
+
+// ReportType a type of a report of a task to the engine
+type ReportType string
+
+const (
+	ReportComplete   ReportType = "complete"
+	ReportBPMNError  ReportType = "bpmnError"
+	ReportEscalation ReportType = "escalation"
+	ReportFailure    ReportType = "failure"
+)
+
+// OutboxEntry a pending report of a task, only the query of the type is set
+type OutboxEntry struct {
+	TaskId     string                                 `json:"taskId"`
+	TopicName  string                                 `json:"topicName"`
+	Type       ReportType                             `json:"type"`
+	Complete   *camundaclientgo.QueryComplete         `json:"complete,omitempty"`
+	BPMNError  *camundaclientgo.QueryHandleBPMNError  `json:"bpmnError,omitempty"`
+	Escalation *camundaclientgo.QueryHandleEscalation `json:"escalation,omitempty"`
+	Failure    *camundaclientgo.QueryHandleFailure    `json:"failure,omitempty"`
+	// Expiration the lock expiration time of the task, the report is lost after it
+	Expiration time.Time `json:"expiration"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// OutboxStore a durable store of pending reports, a task has one pending report at most
+type OutboxStore interface {
+	// Save saves the entry, it replaces an entry of the same task
+	Save(entry *OutboxEntry) error
+	// Delete deletes the entry of the task, it does nothing if the entry doesn't exist
+	Delete(taskId string) error
+	// List returns all saved entries
+	List() ([]*OutboxEntry, error)
+}
+
+// OutboxOptions options of the outbox of reports
+type OutboxOptions struct {
+	// a store of pending reports (default: a FileOutboxStore in Dir)
+	Store OutboxStore
+	// a directory of the default file store (default: DefaultOutboxDir in the working directory)
+	Dir string
+	// maximum delay between delivery attempts of a report (default: DefaultOutboxMaxBackoff)
+	MaxBackoff time.Duration
+	// OnLost is called if a report is not delivered before the lock expiration or the engine rejects it
+	OnLost func(entry *OutboxEntry, err error)
+}
+
+// outbox persists reports of handlers and delivers them until the locks of their tasks expire
+type outbox struct {
+	ctx     context.Context
+	store   OutboxStore
+	client  ExternalTaskClient
+	options OutboxOptions
+	logger  camundaclientgo.Logger
+	// retries running deliveries in background
+	retries sync.WaitGroup
+}
+
+func newOutbox(ctx context.Context, options OutboxOptions, client ExternalTaskClient, logger camundaclientgo.Logger) (*outbox, error) {
+	if options.Store == nil {
+		dir := options.Dir
+		if dir == "" {
+			dir = DefaultOutboxDir
+		}
+
+		store, err := NewFileOutboxStore(dir)
+		if err != nil {
+			return nil, err
+		}
+		options.Store = store
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultOutboxMaxBackoff
+	}
+
+	return &outbox{ctx: ctx, store: options.Store, client: client, options: options, logger: logger}, nil
+}
+
+// redeliver retries delivery of reports saved by a previous run in background
+func (o *outbox) redeliver() {
+	entries, err := o.store.List()
+	if err != nil {
+		o.logger.Error("error list outbox", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		o.retryInBackground(entry, nil)
+	}
+}
+
+// retryInBackground runs retry in background, wait waits for it
+func (o *outbox) retryInBackground(entry *OutboxEntry, lastErr error) {
+	o.retries.Add(1)
+	go func() {
+		defer o.retries.Done()
+		o.retry(entry, lastErr)
+	}()
+}
+
+// wait waits until reports retried in background are delivered or lost, or the ctx is done
+func (o *outbox) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		o.retries.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wrap returns a client of the task which delivers reports through the outbox
+func (o *outbox) wrap(task *camundaclientgo.ResLockedExternalTask, next ExternalTaskClient) ExternalTaskClient {
+	return &outboxClient{outbox: o, task: task, next: next}
+}
+
+// deliver saves the entry and sends it by the client. If the engine is unavailable, the report is retried
+// in background and an error wrapping ErrReportPending is returned. An error of the engine is returned as it is
+func (o *outbox) deliver(entry *OutboxEntry, client ExternalTaskClient) error {
+	if err := o.store.Save(entry); err != nil {
+		o.logger.Error("error save report to outbox", "taskId", entry.TaskId, "topic", entry.TopicName, "error", err)
+		return send(client, entry)
+	}
+
+	err := send(client, entry)
+	if err == nil || !isTransient(err) {
+		o.delete(entry)
+		return err
+	}
+
+	o.logger.Warn("report is not delivered, it is retried", "taskId", entry.TaskId, "topic", entry.TopicName, "type", entry.Type, "error", err)
+	o.retryInBackground(entry, err)
+
+	return fmt.Errorf("%w: %s", ErrReportPending, err)
+}
+
+// retry sends the entry with exponential backoff until it is delivered, rejected or the lock expires.
+// The entry stays in the store if the processor stops before
+func (o *outbox) retry(entry *OutboxEntry, lastErr error) {
+	backoff := 100 * time.Millisecond
+	if backoff > o.options.MaxBackoff {
+		backoff = o.options.MaxBackoff
+	}
+	for {
+		wait := time.Until(entry.Expiration)
+		if wait <= 0 {
+			if lastErr == nil {
+				lastErr = ErrLockExpired
+			}
+			o.lost(entry, lastErr)
+			return
+		}
+		if lastErr != nil && backoff < wait {
+			wait = backoff
+		} else if lastErr == nil {
+			wait = 0
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-o.ctx.Done():
+			return
+		}
+		if time.Now().After(entry.Expiration) {
+			continue
+		}
+
+		lastErr = send(o.client, entry)
+		if lastErr == nil {
+			o.delete(entry)
+			o.logger.Info("report is delivered", "taskId", entry.TaskId, "topic", entry.TopicName, "type", entry.Type)
+			return
+		}
+		if !isTransient(lastErr) {
+			o.lost(entry, lastErr)
+			return
+		}
+
+		backoff *= 2
+		if backoff > o.options.MaxBackoff {
+			backoff = o.options.MaxBackoff
+		}
+	}
+}
+
+func (o *outbox) lost(entry *OutboxEntry, err error) {
+	o.delete(entry)
+	o.logger.Error("report is lost", "taskId", entry.TaskId, "topic", entry.TopicName, "type", entry.Type, "error", err)
+	if o.options.OnLost != nil {
+		o.options.OnLost(entry, err)
+	}
+}
+
+func (o *outbox) delete(entry *OutboxEntry) {
+	if err := o.store.Delete(entry.TaskId); err != nil {
+		o.logger.Error("error delete report from outbox", "taskId", entry.TaskId, "error", err)
+	}
+}
+
+func send(client ExternalTaskClient, entry *OutboxEntry) error {
+	switch entry.Type {
+	case ReportComplete:
+		return client.Complete(entry.TaskId, *entry.Complete)
+	case ReportBPMNError:
+		return client.HandleBPMNError(entry.TaskId, *entry.BPMNError)
+	case ReportEscalation:
+		return client.HandleEscalation(entry.TaskId, *entry.Escalation)
+	case ReportFailure:
+		return client.HandleFailure(entry.TaskId, *entry.Failure)
+	}
+
+	return fmt.Errorf("unknown report type %q", entry.Type)
+}
+
+// isTransient returns true if the request may succeed later: a network error or a 5xx response, e.g. the engine
+// is unavailable behind a proxy. Other errors reject the report
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var responseErr *camundaclientgo.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode >= 500
+	}
+
+	var engineErr *camundaclientgo.Error
+	if errors.As(err, &engineErr) {
+		return engineErr.StatusCode >= 500
+	}
+
+	return false
+}
+
+// outboxClient a client of a task which delivers reports through the outbox
+type outboxClient struct {
+	outbox *outbox
+	task   *camundaclientgo.ResLockedExternalTask
+	next   ExternalTaskClient
+}
+
+func (c *outboxClient) entry(reportType ReportType) *OutboxEntry {
+	expiration, err := time.Parse(camundaclientgo.DefaultDateTimeFormat, c.task.LockExpirationTime)
+	if err != nil {
+		expiration = time.Now().Add(DefaultOutboxExpiration)
+	}
+
+	return &OutboxEntry{
+		TaskId:     c.task.Id,
+		TopicName:  c.task.TopicName,
+		Type:       reportType,
+		Expiration: expiration,
+		CreatedAt:  time.Now(),
+	}
+}
+
+func (c *outboxClient) Complete(id string, query camundaclientgo.QueryComplete) error {
+	entry := c.entry(ReportComplete)
+	entry.TaskId, entry.Complete = id, &query
+	return c.outbox.deliver(entry, c.next)
+}
+
+func (c *outboxClient) HandleBPMNError(id string, query camundaclientgo.QueryHandleBPMNError) error {
+	entry := c.entry(ReportBPMNError)
+	entry.TaskId, entry.BPMNError = id, &query
+	return c.outbox.deliver(entry, c.next)
+}
+
+func (c *outboxClient) HandleEscalation(id string, query camundaclientgo.QueryHandleEscalation) error {
+	entry := c.entry(ReportEscalation)
+	entry.TaskId, entry.Escalation = id, &query
+	return c.outbox.deliver(entry, c.next)
+}
+
+// ExtendLock is sent directly, an extension is useless after a restart
+func (c *outboxClient) ExtendLock(id string, query camundaclientgo.QueryExtendLock) error {
+	return c.next.ExtendLock(id, query)
+}
+
+func (c *outboxClient) HandleFailure(id string, query camundaclientgo.QueryHandleFailure) error {
+	entry := c.entry(ReportFailure)
+	entry.TaskId, entry.Failure = id, &query
+	return c.outbox.deliver(entry, c.next)
+}
+
+// FileOutboxStore an outbox store which saves every entry to a JSON file in the directory
+type FileOutboxStore struct {
+	dir string
+}
+
+// NewFileOutboxStore returns a file store in the directory, the directory is created if it doesn't exist
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't create outbox directory: %w", err)
+	}
+
+	return &FileOutboxStore{dir: dir}, nil
+}
+
+// Save writes the entry to a temporary file and renames it, so a crash doesn't leave a partial entry
+func (s *FileOutboxStore) Save(entry *OutboxEntry) error {
+	return writeJsonFile(s.path(entry.TaskId), entry)
+}
+
+// Delete removes the file of the task
+func (s *FileOutboxStore) Delete(taskId string) error {
+	if err := os.Remove(s.path(taskId)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// List reads all entries of the directory
+func (s *FileOutboxStore) List() ([]*OutboxEntry, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*OutboxEntry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		// #nosec G304 Entries are read from the directory of the store only
+		data, err := ioutil.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entry := &OutboxEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return nil, fmt.Errorf("can't read outbox entry %s: %w", file.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s *FileOutboxStore) path(taskId string) string {
+	return filepath.Join(s.dir, url.PathEscape(taskId)+".json")
+}
+
+// writeJsonFile writes v as JSON to a temporary file in the directory of the path and renames it to the path
+func writeJsonFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
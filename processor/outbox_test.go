@@ -0,0 +1,196 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// flakyClient fails reports with the errors before it accepts them
+type flakyClient struct {
+	fakeClient
+	mu        sync.Mutex
+	errs      []error
+	completes []string
+}
+
+func (c *flakyClient) Complete(id string, _ camundaclientgo.QueryComplete) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return err
+	}
+	c.completes = append(c.completes, id)
+	return nil
+}
+
+func (c *flakyClient) completed() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.completes...)
+}
+
+// errUnavailable a network error of an unavailable engine
+var errUnavailable = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func newTestStore(t *testing.T) *FileOutboxStore {
+	dir, err := ioutil.TempDir("", "outbox")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	store, err := NewFileOutboxStore(dir)
+	require.NoError(t, err)
+	return store
+}
+
+func newTestOutbox(t *testing.T, client ExternalTaskClient, onLost func(*OutboxEntry, error)) *outbox {
+	o, err := newOutbox(context.Background(), OutboxOptions{
+		Store:      newTestStore(t),
+		MaxBackoff: 10 * time.Millisecond,
+		OnLost:     onLost,
+	}, client, camundaclientgo.NopLogger{})
+	require.NoError(t, err)
+	return o
+}
+
+func newLockedTask(id string, lock time.Duration) *camundaclientgo.ResLockedExternalTask {
+	return &camundaclientgo.ResLockedExternalTask{
+		Id:                 id,
+		TopicName:          "payment",
+		LockExpirationTime: time.Now().Add(lock).Format(camundaclientgo.DefaultDateTimeFormat),
+	}
+}
+
+func TestFileOutboxStore(t *testing.T) {
+	store := newTestStore(t)
+	entry := &OutboxEntry{TaskId: "task/1", Type: ReportComplete, Complete: &camundaclientgo.QueryComplete{}}
+	require.NoError(t, store.Save(entry))
+	require.NoError(t, store.Save(entry))
+
+	entries, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "task/1", entries[0].TaskId)
+	assert.Equal(t, ReportComplete, entries[0].Type)
+
+	require.NoError(t, store.Delete("task/1"))
+	require.NoError(t, store.Delete("task/1"))
+	entries, err = store.List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestOutboxRetriesTransientErrors(t *testing.T) {
+	client := &flakyClient{errs: []error{errUnavailable, &camundaclientgo.ResponseError{StatusCode: http.StatusBadGateway}}}
+	o := newTestOutbox(t, client, nil)
+
+	ctx := NewContext(context.Background(), newLockedTask("task", time.Minute), o.wrap(newLockedTask("task", time.Minute), client))
+	err := ctx.Complete(QueryComplete{})
+	assert.True(t, errors.Is(err, ErrReportPending), "the pending report is inspectable")
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, OutcomeComplete, ctx.Outcome())
+	assert.Equal(t, err, Run(ctx, func(ctx *Context) error { return err }, nil))
+	assert.Equal(t, OutcomeComplete, ctx.Outcome(), "no failure is reported")
+
+	require.Eventually(t, func() bool {
+		return len(client.completed()) == 1
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool {
+		entries, _ := o.store.List()
+		return len(entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestOutboxReportsLostResults(t *testing.T) {
+	lost := make(chan error, 2)
+	onLost := func(entry *OutboxEntry, err error) {
+		lost <- err
+	}
+
+	// the engine rejects the report
+	rejected := &camundaclientgo.Error{Type: "RestException", Message: "task is locked by another worker"}
+	client := &flakyClient{errs: []error{rejected}}
+	o := newTestOutbox(t, client, onLost)
+	assert.Equal(t, rejected, o.wrap(newLockedTask("task", time.Minute), client).Complete("task", camundaclientgo.QueryComplete{}))
+
+	// the lock expires
+	unavailable := &camundaclientgo.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	client = &flakyClient{errs: []error{unavailable, unavailable, unavailable, unavailable, unavailable}}
+	o = newTestOutbox(t, client, onLost)
+	err := o.wrap(newLockedTask("task", 20*time.Millisecond), client).Complete("task", camundaclientgo.QueryComplete{})
+	assert.True(t, errors.Is(err, ErrReportPending))
+	select {
+	case err := <-lost:
+		assert.Equal(t, unavailable, err)
+	case <-time.After(time.Second):
+		t.Fatal("the lost report is not reported")
+	}
+	assert.Empty(t, client.completed())
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, isTransient(errUnavailable))
+	assert.True(t, isTransient(&url.Error{Op: "Post", URL: "http://engine", Err: errUnavailable}))
+	assert.True(t, isTransient(&camundaclientgo.ResponseError{StatusCode: http.StatusBadGateway}))
+	assert.True(t, isTransient(&camundaclientgo.Error{Type: "ProcessEngineException", StatusCode: http.StatusInternalServerError}))
+	assert.False(t, isTransient(&camundaclientgo.Error{Type: "RestException", StatusCode: http.StatusBadRequest}))
+	assert.False(t, isTransient(&camundaclientgo.ResponseError{StatusCode: http.StatusForbidden}))
+	assert.False(t, isTransient(errors.New("json: unsupported value")))
+}
+
+func TestOutboxShutdownWaitsForRetries(t *testing.T) {
+	client := &flakyClient{errs: []error{errUnavailable, errUnavailable}}
+	o := newTestOutbox(t, client, nil)
+	err := o.wrap(newLockedTask("task", time.Minute), client).Complete("task", camundaclientgo.QueryComplete{})
+	require.True(t, errors.Is(err, ErrReportPending))
+
+	require.NoError(t, o.wait(context.Background()))
+	assert.Equal(t, []string{"task"}, client.completed(), "the report is delivered before wait returns")
+
+	client = &flakyClient{errs: []error{errUnavailable, errUnavailable, errUnavailable, errUnavailable}}
+	ctx, cancel := context.WithCancel(context.Background())
+	o, err = newOutbox(ctx, OutboxOptions{Store: newTestStore(t), MaxBackoff: time.Second}, client, camundaclientgo.NopLogger{})
+	require.NoError(t, err)
+	require.Error(t, o.wrap(newLockedTask("task", time.Minute), client).Complete("task", camundaclientgo.QueryComplete{}))
+
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelWait()
+	assert.Equal(t, context.DeadlineExceeded, o.wait(waitCtx))
+	cancel()
+	require.NoError(t, o.wait(context.Background()), "retries stop with the processor")
+	entries, err := o.store.List()
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the report is kept for the next run")
+}
+
+func TestOutboxRedeliversSavedReports(t *testing.T) {
+	client := &flakyClient{}
+	o := newTestOutbox(t, client, nil)
+	require.NoError(t, o.store.Save(&OutboxEntry{
+		TaskId:     "saved",
+		Type:       ReportComplete,
+		Complete:   &camundaclientgo.QueryComplete{},
+		Expiration: time.Now().Add(time.Minute),
+	}))
+
+	o.redeliver()
+	require.Eventually(t, func() bool {
+		return len(client.completed()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"saved"}, client.completed())
+}
@@ -1,10 +1,12 @@
 package processor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
-	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
@@ -12,9 +14,37 @@ import (
 
 // Processor external task processor
 type Processor struct {
-	client  *camundaclientgo.Client
-	options *Options
-	logger  func(err error)
+	client      *camundaclientgo.Client
+	options     *Options
+	logger      camundaclientgo.Logger
+	tracer      camundaclientgo.Tracer
+	metrics     Metrics
+	middlewares []Middleware
+
+	mu sync.Mutex
+	// worker pools of handlers by topic name
+	pools map[string]*handlerPool
+	// fetch groups of handlers with the same fetch options in the order of registration
+	groups []*fetchGroup
+	// fetch states of topics by topic name
+	fetchStates map[string]*fetchState
+	// changed is closed when a worker slot is freed or a topic is added
+	changed chan struct{}
+	// reserved number of fetched tasks which are dispatched or handled by all pools
+	reserved int32
+	// slots limits parallel tasks of all handlers if MaxParallelTasks is set
+	slots chan struct{}
+	// ctx is cancelled by Shutdown, it cancels pending fetch requests
+	ctx      context.Context
+	cancel   context.CancelFunc
+	draining bool
+	// handlersCtx is cancelled if Shutdown times out, it cancels contexts of handlers
+	handlersCtx    context.Context
+	cancelHandlers context.CancelFunc
+	// outbox delivers reports of handlers if Options.Outbox is set
+	outbox *outbox
+	// dedup the Dedup middleware of all handlers if Options.Dedup is set
+	dedup Middleware
 }
 
 // Options options for Processor
@@ -23,10 +53,16 @@ type Options struct {
 	WorkerId string
 	// lock duration for all external task
 	LockDuration time.Duration
-	// maximum tasks to receive for 1 request to camunda
+	// maximum tasks to receive for 1 request to camunda, fewer tasks are requested if handlers have fewer free workers
 	MaxTasks int
+	// maximum time a fetched task waits for a free worker of its handler, the task is unlocked after it (default: 10 seconds)
+	DispatchTimeout time.Duration
+	// number of fetch loops, every loop requests tasks of all topics by one long polling request (default: 1)
+	FetchLoops int
 	// maximum running parallel task per handler
 	MaxParallelTaskPerHandler int
+	// maximum running parallel tasks of all handlers, free workers are shared by weights of handlers (default: no limit)
+	MaxParallelTasks int
 	// use priority
 	UsePriority *bool
 	// long polling timeout
@@ -35,9 +71,38 @@ type Options struct {
 	AsyncResponseTimeout *int
 	// long polling timeout
 	LongPollingTimeout time.Duration
+	// tracer for fetch-and-lock cycles and handled tasks (default: tracer of the client)
+	Tracer camundaclientgo.Tracer
+	// metrics of fetching and handling per topic (default: no metrics)
+	Metrics Metrics
+	// structured logger, it replaces the logger func passed to NewProcessor
+	Logger camundaclientgo.Logger
+	// disable the default Recovery middleware, a panic of a handler crashes the program
+	DisableRecovery bool
+	// retry policy of failed tasks (default: retries are not set, the engine decides)
+	RetryPolicy *RetryPolicy
+	// retry policies per topic, they override RetryPolicy
+	TopicRetryPolicies map[string]RetryPolicy
+	// safety margin between the deadline of a handler and the lock expiration of its task, at most a half
+	// of the remaining lock time (default: 1 second)
+	LockMargin time.Duration
+	// disable deadlines of handlers by lock expiration, the timeout of a handler still applies
+	DisableLockDeadline bool
+	// DeadlineError returns the error reported as a failure if a handler overruns its deadline, e.g. an Incident.
+	// err wraps context.DeadlineExceeded (default: err is reported with retries by the retry policy)
+	DeadlineError func(ctx *Context, err error) error
+	// persist reports of handlers and retry their delivery while the engine is unavailable (default: disabled)
+	Outbox *OutboxOptions
+	// run handlers once per task, results of processed tasks are reported again (default: disabled)
+	Dedup *DedupOptions
+	// callbacks of lifecycle events (default: no callbacks)
+	Hooks *Hooks
+	// number of consecutive failed fetch requests of a topic which make the processor unhealthy (default: 3)
+	HealthFetchErrors int
 }
 
-// NewProcessor a create new instance Processor
+// NewProcessor a create new instance Processor.
+// The logger receives warnings and errors if Options.Logger is not set
 func NewProcessor(client *camundaclientgo.Client, options *Options, logger func(err error)) *Processor {
 	rand.Seed(time.Now().UnixNano())
 	if options.WorkerId == "" {
@@ -45,149 +110,677 @@ func NewProcessor(client *camundaclientgo.Client, options *Options, logger func(
 		options.WorkerId = fmt.Sprintf("worker-%d", rand.Int())
 	}
 
-	return &Processor{
-		client:  client,
-		options: options,
-		logger:  logger,
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = client.Tracer()
+	}
+
+	var structuredLogger camundaclientgo.Logger = camundaclientgo.NopLogger{}
+	if options.Logger != nil {
+		structuredLogger = options.Logger
+	} else if logger != nil {
+		structuredLogger = camundaclientgo.NewErrorFuncLogger(logger)
+	}
+
+	metrics := options.Metrics
+	if metrics == nil {
+		metrics = NoopMetrics{}
+	}
+
+	var slots chan struct{}
+	if options.MaxParallelTasks > 0 {
+		slots = make(chan struct{}, options.MaxParallelTasks)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handlersCtx, cancelHandlers := context.WithCancel(context.Background())
+
+	p := &Processor{
+		ctx:            ctx,
+		cancel:         cancel,
+		handlersCtx:    handlersCtx,
+		cancelHandlers: cancelHandlers,
+		client:         client,
+		options:        options,
+		logger:         structuredLogger,
+		tracer:         tracer,
+		metrics:        metrics,
+		pools:          map[string]*handlerPool{},
+		fetchStates:    map[string]*fetchState{},
+		changed:        make(chan struct{}),
+		slots:          slots,
+	}
+
+	if options.Dedup != nil {
+		p.dedup = Dedup(*options.Dedup)
 	}
+
+	if options.Outbox != nil {
+		outbox, err := newOutbox(handlersCtx, *options.Outbox, client.ExternalTask, structuredLogger)
+		if err != nil {
+			structuredLogger.Error("outbox is disabled", "error", err)
+		} else {
+			p.outbox = outbox
+			outbox.redeliver()
+		}
+	}
+
+	return p
+}
+
+// HandlerOptions options of a handler, they override Options of the processor
+type HandlerOptions struct {
+	// lock duration of tasks of the handler
+	LockDuration time.Duration
+	// maximum tasks of the handler to receive for 1 request to camunda
+	MaxTasks int
+	// maximum running parallel tasks of the handler
+	MaxParallelTasks int
+	// use priority, handlers with another value than the processor are fetched by separate fetch loops
+	UsePriority *bool
+	// long polling timeout, handlers with another value than the processor are fetched by separate fetch loops
+	LongPollingTimeout time.Duration
+	// timeout of the handler, its context is cancelled and a failure is reported after it, the handler keeps its
+	// worker until it returns. The deadline of the lock expiration applies too, it moves when the lock is extended
+	Timeout time.Duration
+	// weight of the handler in sharing of free workers of the processor limited by MaxParallelTasks
+	// or MaxTasks (default: 1)
+	Weight int
 }
 
 // Handler a handler for external task
 type Handler func(ctx *Context) error
 
+// handlerPool a worker pool of a handler, it receives tasks of all topics of the handler
+type handlerPool struct {
+	handler  Handler
+	capacity int
+	inFlight int32
+	// maxTasks maximum tasks of the pool for 1 request, 0 is no limit
+	maxTasks int
+	weight   int
+	timeout  time.Duration
+	// reserved number of fetched tasks which are dispatched or handled by the pool
+	reserved int32
+	tasks    chan *camundaclientgo.ResLockedExternalTask
+	// stopped is closed when the pool is stopped, workers exit after their tasks and pending tasks are unlocked
+	stopped chan struct{}
+	// paused and removed are guarded by Processor.mu
+	paused    bool
+	removed   bool
+	closeOnce sync.Once
+}
+
+// close stops workers of the pool, in-flight tasks are handled and tasks waiting for a worker are unlocked
+func (pool *handlerPool) close() {
+	pool.closeOnce.Do(func() {
+		close(pool.stopped)
+	})
+}
+
+// freeSlots returns the number of tasks the pool can receive without waiting
+func (pool *handlerPool) freeSlots() int {
+	free := pool.capacity - int(atomic.LoadInt32(&pool.reserved))
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// Outcome a result of external task handling reported to the engine
+type Outcome string
+
+const (
+	// OutcomeNone the handler reported nothing to the engine
+	OutcomeNone Outcome = "none"
+	// OutcomeComplete the task is completed
+	OutcomeComplete Outcome = "complete"
+	// OutcomeBPMNError the BPMN error is reported
+	OutcomeBPMNError Outcome = "bpmn_error"
+	// OutcomeEscalation the escalation is reported
+	OutcomeEscalation Outcome = "escalation"
+	// OutcomeFailure the failure is reported
+	OutcomeFailure Outcome = "failure"
+	// OutcomePanic the handler panicked, the failure is reported if possible
+	OutcomePanic Outcome = "panic"
+)
+
+// ExternalTaskClient reports results of external tasks to the engine, it is implemented by the ExternalTask api
+// of the client. Use a fake implementation to test handlers without an engine
+type ExternalTaskClient interface {
+	Complete(id string, query camundaclientgo.QueryComplete) error
+	HandleBPMNError(id string, query camundaclientgo.QueryHandleBPMNError) error
+	HandleEscalation(id string, query camundaclientgo.QueryHandleEscalation) error
+	HandleFailure(id string, query camundaclientgo.QueryHandleFailure) error
+	ExtendLock(id string, query camundaclientgo.QueryExtendLock) error
+}
+
+// ErrAbandoned is returned by reports of a handler which did not return before its timeout or deadline,
+// the failure of the task is reported already
+var ErrAbandoned = errors.New("handler is abandoned after its deadline, the task is reported already")
+
 // Context external task context
 type Context struct {
 	Task   *camundaclientgo.ResLockedExternalTask
-	client *camundaclientgo.Client
+	client ExternalTaskClient
+	ctx    context.Context
+	state  *contextState
+	hooks  *Hooks
+	// gate rejects reports of an abandoned handler, nil accepts all reports
+	gate *reportGate
+}
+
+// contextState a state of the task handling shared by copies of the context
+type contextState struct {
+	mu      sync.Mutex
+	outcome Outcome
+	// abandoned handlers running in background, the worker of the task waits for them
+	abandoned sync.WaitGroup
+	// lockLost is set when OnLockLost is called, it is called once per task
+	lockLost bool
+	// lockTimer calls OnLockLost when the lock expires while the handler runs, see watchLock
+	lockTimer   *time.Timer
+	lockWatched bool
+	// deadline of the handler, it moves when the lock is extended. Nil if the processor doesn't run the handler
+	deadline *deadlineContext
+}
+
+// reportGate rejects reports of a handler after it is abandoned. A report holds the gate and its parents
+// until it is sent, so the handler can't report a result while its failure is reported
+type reportGate struct {
+	mu     sync.Mutex
+	closed bool
+	parent *reportGate
+}
+
+// enter locks the gate and its parents, it returns ErrAbandoned if one of them is closed
+func (g *reportGate) enter() (func(), error) {
+	var locked []*reportGate
+	unlock := func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].mu.Unlock()
+		}
+	}
+
+	for gate := g; gate != nil; gate = gate.parent {
+		gate.mu.Lock()
+		locked = append(locked, gate)
+		if gate.closed {
+			unlock()
+			return nil, ErrAbandoned
+		}
+	}
+
+	return unlock, nil
+}
+
+// close rejects reports after the call, it waits for a report in progress
+func (g *reportGate) close() {
+	g.mu.Lock()
+	g.closed = true
+	g.mu.Unlock()
+}
+
+// NewContext returns a context of the locked task which reports results by the client,
+// e.g. to run a handler in tests
+func NewContext(ctx context.Context, task *camundaclientgo.ResLockedExternalTask, client ExternalTaskClient) *Context {
+	return &Context{
+		Task:   task,
+		client: client,
+		ctx:    ctx,
+		state:  &contextState{outcome: OutcomeNone},
+	}
+}
+
+// Context returns the context of the task handling, it carries the span of the task
+func (c *Context) Context() context.Context {
+	return c.ctx
+}
+
+// WithContext returns a copy of the context which carries ctx, e.g. with a deadline or a span.
+// The result reported by the copy is the result of the task
+func (c *Context) WithContext(ctx context.Context) *Context {
+	copied := *c
+	copied.ctx = ctx
+
+	return &copied
+}
+
+// Outcome returns the result reported to the engine so far
+func (c *Context) Outcome() Outcome {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+
+	return c.state.outcome
+}
+
+// reported returns true if the report is sent or pending in the outbox
+func reported(err error) bool {
+	return err == nil || errors.Is(err, ErrReportPending)
+}
+
+func (c *Context) setOutcome(outcome Outcome) {
+	c.state.mu.Lock()
+	c.state.outcome = outcome
+	c.state.mu.Unlock()
 }
 
 // Complete a mark external task is complete
 func (c *Context) Complete(query QueryComplete) error {
-	return c.client.ExternalTask.Complete(c.Task.Id, camundaclientgo.QueryComplete{
+	unlock, err := c.gate.enter()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = c.client.Complete(c.Task.Id, camundaclientgo.QueryComplete{
 		WorkerId:       &c.Task.WorkerId,
 		Variables:      query.Variables,
 		LocalVariables: query.LocalVariables,
 	})
+	if reported(err) {
+		c.setOutcome(OutcomeComplete)
+	}
+
+	return err
 }
 
 // HandleBPMNError handle external task BPMN error
 func (c *Context) HandleBPMNError(query QueryHandleBPMNError) error {
-	return c.client.ExternalTask.HandleBPMNError(c.Task.Id, camundaclientgo.QueryHandleBPMNError{
+	unlock, err := c.gate.enter()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = c.client.HandleBPMNError(c.Task.Id, camundaclientgo.QueryHandleBPMNError{
 		WorkerId:     &c.Task.WorkerId,
 		ErrorCode:    query.ErrorCode,
 		ErrorMessage: query.ErrorMessage,
 		Variables:    query.Variables,
 	})
+	if reported(err) {
+		c.setOutcome(OutcomeBPMNError)
+	}
+
+	return err
+}
+
+// HandleEscalation handle external task escalation
+func (c *Context) HandleEscalation(query QueryHandleEscalation) error {
+	unlock, err := c.gate.enter()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = c.client.HandleEscalation(c.Task.Id, camundaclientgo.QueryHandleEscalation{
+		WorkerId:       &c.Task.WorkerId,
+		EscalationCode: query.EscalationCode,
+		Variables:      query.Variables,
+	})
+	if reported(err) {
+		c.setOutcome(OutcomeEscalation)
+	}
+
+	return err
 }
 
 // HandleFailure handle external task failure
 func (c *Context) HandleFailure(query QueryHandleFailure) error {
-	return c.client.ExternalTask.HandleFailure(c.Task.Id, camundaclientgo.QueryHandleFailure{
+	unlock, err := c.gate.enter()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = c.client.HandleFailure(c.Task.Id, camundaclientgo.QueryHandleFailure{
 		WorkerId:     &c.Task.WorkerId,
 		ErrorMessage: query.ErrorMessage,
 		ErrorDetails: query.ErrorDetails,
 		Retries:      query.Retries,
 		RetryTimeout: query.RetryTimeout,
 	})
+	if reported(err) {
+		c.setOutcome(OutcomeFailure)
+	}
+
+	return err
 }
 
-// AddHandler a add handler for external task
-func (p *Processor) AddHandler(topics []*camundaclientgo.QueryFetchAndLockTopic, handler Handler) {
-	if topics != nil && p.options.LockDuration != 0 {
+// ExtendLock extends the lock of the task by the duration starting from now.
+// The deadline of the handler moves to the new lock expiration minus Options.LockMargin,
+// the timeout of the handler still applies
+func (c *Context) ExtendLock(duration time.Duration) error {
+	unlock, err := c.gate.enter()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// the engine locks the task from the time it receives the request
+	expiration := time.Now().Add(duration)
+	if c.state.deadline != nil {
+		extended := c.state.deadline.extendLock()
+		defer func() {
+			extended(expiration, err == nil)
+		}()
+	}
+
+	newDuration := int(duration / time.Millisecond)
+	err = c.client.ExtendLock(c.Task.Id, camundaclientgo.QueryExtendLock{
+		NewDuration: &newDuration,
+		WorkerId:    &c.Task.WorkerId,
+	})
+
+	event := TaskEvent{Task: c.Task, Time: time.Now(), LockDuration: duration, Err: err}
+	if err == nil {
+		c.resetLockWatch(expiration)
+		c.hooks.lockExtended(event)
+	} else if !isTransient(err) {
+		c.lockLost(event)
+	}
+
+	return err
+}
+
+// ErrLockExpired is the error of the OnLockLost event of a task whose lock expired while its handler was running
+var ErrLockExpired = errors.New("lock of the task is expired")
+
+// lockLost calls OnLockLost once per task
+func (c *Context) lockLost(event TaskEvent) {
+	c.state.mu.Lock()
+	lost := c.state.lockLost
+	c.state.lockLost = true
+	c.state.mu.Unlock()
+
+	if !lost {
+		c.hooks.lockLost(event)
+	}
+}
+
+// watchLock calls OnLockLost if the lock expires before the handler reported a result and stopLockWatch is called
+func (c *Context) watchLock(expiration time.Time) {
+	c.state.mu.Lock()
+	c.state.lockWatched = true
+	c.state.mu.Unlock()
+
+	c.resetLockWatch(expiration)
+}
+
+// resetLockWatch moves the lock expiration of a watched lock
+func (c *Context) resetLockWatch(expiration time.Time) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+
+	if !c.state.lockWatched {
+		return
+	}
+	if c.state.lockTimer != nil {
+		c.state.lockTimer.Stop()
+	}
+	c.state.lockTimer = time.AfterFunc(time.Until(expiration), func() {
+		if c.Outcome() == OutcomeNone {
+			c.lockLost(TaskEvent{Task: c.Task, Time: time.Now(), Err: ErrLockExpired})
+		}
+	})
+}
+
+// stopLockWatch stops watching the lock after the handler returned
+func (c *Context) stopLockWatch() {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+
+	c.state.lockWatched = false
+	if c.state.lockTimer != nil {
+		c.state.lockTimer.Stop()
+	}
+}
+
+// Use adds middlewares to all handlers added after the call, the first middleware is the outermost one
+func (p *Processor) Use(middlewares ...Middleware) {
+	p.middlewares = append(p.middlewares, middlewares...)
+}
+
+// AddHandler a add handler for external task.
+// The handler is wrapped by the Recovery middleware, the Dedup middleware if Options.Dedup is set,
+// middlewares of the processor and the middlewares.
+// Tasks of the topics are fetched by the shared fetch loops together with topics of other handlers,
+// a topic can be handled by one handler only. The returned subscription pauses, resumes and removes the handler
+func (p *Processor) AddHandler(topics []*camundaclientgo.QueryFetchAndLockTopic, handler Handler, middlewares ...Middleware) *Subscription {
+	return p.AddHandlerWithOptions(topics, handler, HandlerOptions{}, middlewares...)
+}
+
+// AddHandlerWithOptions adds a handler for external task like AddHandler, the options override options of the processor
+func (p *Processor) AddHandlerWithOptions(topics []*camundaclientgo.QueryFetchAndLockTopic, handler Handler, options HandlerOptions, middlewares ...Middleware) *Subscription {
+	chain := append([]Middleware{}, p.middlewares...)
+	if p.dedup != nil {
+		chain = append([]Middleware{p.dedup}, chain...)
+	}
+	if !p.options.DisableRecovery {
+		chain = append([]Middleware{Recovery()}, chain...)
+	}
+	handler = Chain(handler, append(chain, middlewares...)...)
+
+	lockDuration := p.options.LockDuration
+	if options.LockDuration > 0 {
+		lockDuration = options.LockDuration
+	}
+	if lockDuration != 0 {
 		for _, v := range topics {
 			if v.LockDuration <= 0 {
-				v.LockDuration = int(p.options.LockDuration / time.Millisecond)
+				v.LockDuration = int(lockDuration / time.Millisecond)
 			}
 		}
 	}
 
-	var asyncResponseTimeout *int
-	if p.options.AsyncResponseTimeout != nil {
-		asyncResponseTimeout = p.options.AsyncResponseTimeout
-	} else if p.options.LongPollingTimeout.Nanoseconds() > 0 {
-		msValue := int(p.options.LongPollingTimeout.Nanoseconds() / int64(time.Millisecond))
-		asyncResponseTimeout = &msValue
+	maxParallelTasks := p.options.MaxParallelTaskPerHandler
+	if options.MaxParallelTasks > 0 {
+		maxParallelTasks = options.MaxParallelTasks
+	}
+	if maxParallelTasks < 1 {
+		maxParallelTasks = 1
 	}
 
-	go p.startPuller(camundaclientgo.QueryFetchAndLock{
-		WorkerId:             p.options.WorkerId,
-		MaxTasks:             p.options.MaxTasks,
-		UsePriority:          p.options.UsePriority,
-		AsyncResponseTimeout: asyncResponseTimeout,
-		Topics:               topics,
-	}, handler)
-}
-
-func (p *Processor) startPuller(query camundaclientgo.QueryFetchAndLock, handler Handler) {
-	var tasksChan = make(chan *camundaclientgo.ResLockedExternalTask)
-
-	maxParallelTaskPerHandler := p.options.MaxParallelTaskPerHandler
-	if maxParallelTaskPerHandler < 1 {
-		maxParallelTaskPerHandler = 1
+	weight := options.Weight
+	if weight < 1 {
+		weight = 1
 	}
 
 	// create worker pool
-	for i := 0; i < maxParallelTaskPerHandler; i++ {
-		go p.runWorker(handler, tasksChan)
+	pool := &handlerPool{
+		handler:  handler,
+		capacity: maxParallelTasks,
+		maxTasks: options.MaxTasks,
+		weight:   weight,
+		timeout:  options.Timeout,
+		tasks:    make(chan *camundaclientgo.ResLockedExternalTask),
+		stopped:  make(chan struct{}),
+	}
+	for i := 0; i < maxParallelTasks; i++ {
+		go p.runWorker(pool)
 	}
 
-	retries := 0
-	for {
-		tasks, err := p.client.ExternalTask.FetchAndLock(query)
-		if err != nil {
-			if retries < 60 {
-				retries += 1
-			}
-			p.logger(fmt.Errorf("failed pull: %w, sleeping: %d seconds", err, retries))
-			time.Sleep(time.Duration(retries) * time.Second)
+	p.mu.Lock()
+	group, created := p.fetchGroup(options)
+	subscription := &Subscription{p: p, pool: pool, group: group}
+	for _, topic := range topics {
+		if _, ok := p.pools[topic.TopicName]; ok {
+			p.logger.Error("topic already has a handler, it is ignored", "topic", topic.TopicName)
 			continue
 		}
-		retries = 0
+		p.pools[topic.TopicName] = pool
+		group.topics = append(group.topics, topic)
+		subscription.topics = append(subscription.topics, topic.TopicName)
+	}
+	p.notifyLocked()
+	p.mu.Unlock()
 
-		for _, task := range tasks {
-			tasksChan <- task
+	if created {
+		fetchLoops := p.options.FetchLoops
+		if fetchLoops < 1 {
+			fetchLoops = 1
+		}
+		for i := 0; i < fetchLoops; i++ {
+			go p.runFetchLoop(group)
 		}
 	}
+
+	return subscription
 }
 
-func (p *Processor) runWorker(handler Handler, tasksChan chan *camundaclientgo.ResLockedExternalTask) {
-	for task := range tasksChan {
-		p.handle(&Context{
-			Task:   task,
-			client: p.client,
-		}, handler)
+func (p *Processor) runWorker(pool *handlerPool) {
+	for {
+		select {
+		case task := <-pool.tasks:
+			p.handleTask(task, pool)
+			p.release(pool, true)
+		case <-pool.stopped:
+			return
+		}
 	}
 }
 
-func (p *Processor) handle(ctx *Context, handler Handler) {
-	defer func() {
-		if r := recover(); r != nil {
-			errMessage := fmt.Sprintf("fatal error in task: %s", r)
-			errDetails := fmt.Sprintf("fatal error in task: %s\nStack trace: %s", r, string(debug.Stack()))
-			err := ctx.HandleFailure(QueryHandleFailure{
-				ErrorMessage: &errMessage,
-				ErrorDetails: &errDetails,
-			})
-
-			if err != nil {
-				p.logger(fmt.Errorf("error send handle failure: %w", err))
-			}
+func (p *Processor) handleTask(task *camundaclientgo.ResLockedExternalTask, pool *handlerPool) {
+	startedAt := time.Now()
+	p.metrics.TaskStarted(task.TopicName, int(atomic.AddInt32(&pool.inFlight, 1)), pool.capacity)
 
-			p.logger(errors.New(errDetails))
-		}
+	ctx, span := p.tracer.Start(context.Background(), "ExternalTask handle "+task.TopicName, camundaclientgo.SpanKindConsumer)
+	defer span.End()
+	span.SetAttributes(
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeTopicName, Value: task.TopicName},
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeTaskId, Value: task.Id},
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeProcessInstanceId, Value: task.ProcessInstanceId},
+		camundaclientgo.Attribute{Key: camundaclientgo.AttributeBusinessKey, Value: task.BusinessKey},
+	)
+	if task.Retries != nil {
+		span.SetAttributes(camundaclientgo.Attribute{Key: camundaclientgo.AttributeTaskRetries, Value: *task.Retries})
+	}
+
+	// the deadline doesn't apply to reports of the task, a failure is reported after the deadline
+	taskCtx, cancel := p.handlerContext(ctx, task, pool)
+	defer cancel()
+	handler := pool.handler
+	if _, hasDeadline := taskCtx.Deadline(); hasDeadline {
+		handler = p.withDeadline(handler)
+	}
+
+	var client ExternalTaskClient = p.client.WithContext(ctx).ExternalTask
+	if p.outbox != nil {
+		client = p.outbox.wrap(task, client)
+	}
+
+	handlerCtx := NewContext(taskCtx, task, client)
+	handlerCtx.hooks = p.options.Hooks
+	handlerCtx.state.deadline = taskCtx
+	if lockExpiration, err := time.Parse(camundaclientgo.DefaultDateTimeFormat, task.LockExpirationTime); err == nil {
+		handlerCtx.watchLock(lockExpiration)
+	}
+	// run waits for abandoned handlers, so the lock is watched until they return
+	defer handlerCtx.stopLockWatch()
+	p.logger.Debug("task started", taskLogFields(task)...)
+	defer func() {
+		span.SetAttributes(camundaclientgo.Attribute{Key: camundaclientgo.AttributeTaskOutcome, Value: string(handlerCtx.Outcome())})
+		inFlight := int(atomic.AddInt32(&pool.inFlight, -1))
+		duration := time.Since(startedAt)
+		p.metrics.TaskFinished(task.TopicName, handlerCtx.Outcome(), duration, inFlight, pool.capacity)
+		p.logger.Debug("task finished", append(taskLogFields(task), "outcome", handlerCtx.Outcome(), "duration", duration)...)
 	}()
 
+	p.options.Hooks.handlerStarted(TaskEvent{Task: task, Time: startedAt})
+	err := p.handle(handlerCtx, handler)
+	p.options.Hooks.taskFinished(TaskEvent{
+		Task:     task,
+		Time:     time.Now(),
+		Duration: time.Since(startedAt),
+		Outcome:  handlerCtx.Outcome(),
+		Err:      err,
+	})
+	if outcome := handlerCtx.Outcome(); err != nil && outcome != OutcomeBPMNError && outcome != OutcomeEscalation {
+		span.RecordError(err)
+	}
+}
+
+// handle runs the handler and reports a failure to the engine if the handler returns an error,
+// BPMNError and Escalation are reported as a BPMN error and an escalation. It returns the error of the handler
+func (p *Processor) handle(ctx *Context, handler Handler) error {
+	return run(ctx, handler, p.retryPolicy(ctx.Task.TopicName), p.logger)
+}
+
+// retryPolicy returns the retry policy of the topic, nil if no policy is configured
+func (p *Processor) retryPolicy(topic string) *RetryPolicy {
+	if policy, ok := p.options.TopicRetryPolicies[topic]; ok {
+		return &policy
+	}
+
+	return p.options.RetryPolicy
+}
+
+// Run runs the handler like the processor does: BPMNError and Escalation returned by the handler are reported
+// as they are, if the handler returns another error or panics, a failure is reported to the engine
+// with retries by the policy (may be nil). It returns the error of the handler
+func Run(ctx *Context, handler Handler, policy *RetryPolicy) error {
+	return run(ctx, Recovery()(handler), policy, camundaclientgo.NopLogger{})
+}
+
+func run(ctx *Context, handler Handler, policy *RetryPolicy, logger camundaclientgo.Logger) error {
+	// the worker of the task is held until abandoned handlers return
+	defer ctx.state.abandoned.Wait()
+
 	err := handler(ctx)
-	if err != nil {
-		errMessage := fmt.Sprintf("task error: %s", err)
-		err = ctx.HandleFailure(QueryHandleFailure{
-			ErrorMessage: &errMessage,
-		})
+	if reported(err) {
+		return err
+	}
 
-		if err != nil {
-			p.logger(fmt.Errorf("error send handle failure: %w", err))
+	var bpmnError *BPMNError
+	if errors.As(err, &bpmnError) {
+		if reportErr := ctx.HandleBPMNError(bpmnError.query()); !reported(reportErr) {
+			logger.Error("error send BPMN error", append(taskLogFields(ctx.Task), "error", reportErr)...)
+		}
+		return err
+	}
+
+	var escalation *Escalation
+	if errors.As(err, &escalation) {
+		if reportErr := ctx.HandleEscalation(escalation.query()); !reported(reportErr) {
+			logger.Error("error send escalation", append(taskLogFields(ctx.Task), "error", reportErr)...)
 		}
+		return err
+	}
+
+	failure := failureQuery(ctx.Task, err, policy)
+	errMessage := fmt.Sprintf("task error: %s", err)
+	logMessage := errMessage
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		errMessage = panicErr.Error()
+		logMessage = fmt.Sprintf("%s\nStack trace: %s", errMessage, panicErr.Stack)
+		failure.ErrorDetails = &logMessage
+	}
+	failure.ErrorMessage = &errMessage
+
+	failureErr := ctx.HandleFailure(failure)
+	if panicErr != nil {
+		ctx.setOutcome(OutcomePanic)
+	}
+
+	if !reported(failureErr) {
+		logger.Error("error send handle failure", append(taskLogFields(ctx.Task), "error", failureErr)...)
+	}
+
+	logger.Error(logMessage, taskLogFields(ctx.Task)...)
+
+	return err
+}
 
-		p.logger(errors.New(errMessage))
+// taskLogFields returns log fields which identify the task
+func taskLogFields(task *camundaclientgo.ResLockedExternalTask) []interface{} {
+	return []interface{}{
+		"topic", task.TopicName,
+		"taskId", task.Id,
+		"workerId", task.WorkerId,
+		"processInstanceId", task.ProcessInstanceId,
+		"businessKey", task.BusinessKey,
 	}
 }
@@ -0,0 +1,587 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+	"github.com/wurenquyu/camunda-client-go/v3/camundatest"
+	"github.com/wurenquyu/camunda-client-go/v3/processor"
+)
+
+func TestProcessor(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+
+	completed := engine.AddExternalTask("greet", map[string]camundaclientgo.Variable{
+		"name": {Value: "World", Type: "String"},
+	})
+	failed := engine.AddExternalTask("fail", nil)
+	rejected := engine.AddExternalTask("reject", nil)
+
+	client := engine.Client()
+	fetches := &fetchCounter{next: http.DefaultTransport}
+	client.SetCustomTransport(fetches)
+
+	proc := processor.NewProcessor(client, &processor.Options{
+		WorkerId:                  "test-worker",
+		LockDuration:              time.Second,
+		MaxTasks:                  10,
+		MaxParallelTaskPerHandler: 1,
+		LongPollingTimeout:        time.Second,
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "greet"}}, func(ctx *processor.Context) error {
+		return ctx.Complete(processor.QueryComplete{LocalVariables: &map[string]camundaclientgo.Variable{
+			"greeting": {Value: "Hello, " + ctx.Task.Variables["name"].Value.(string), Type: "String"},
+		}})
+	})
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "fail"}}, func(ctx *processor.Context) error {
+		return errors.New("boom")
+	})
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "reject"}}, func(ctx *processor.Context) error {
+		return processor.NewBPMNError("rejected", "the order is rejected")
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		completedTask, _ := engine.ExternalTask(completed.Id)
+		failedTask, _ := engine.ExternalTask(failed.Id)
+		rejectedTask, _ := engine.ExternalTask(rejected.Id)
+		return completedTask.State == camundatest.ExternalTaskCompleted && failedTask.Failures > 0 &&
+			rejectedTask.State == camundatest.ExternalTaskBPMNError
+	}))
+
+	if engine.AssertExternalTaskCompleted(t, completed.Id) {
+		task, _ := engine.ExternalTask(completed.Id)
+		assert.Equal(t, "Hello, World", task.ResultLocalVariables["greeting"].Value)
+	}
+	if engine.AssertExternalTaskFailed(t, failed.Id) {
+		task, _ := engine.ExternalTask(failed.Id)
+		assert.Equal(t, "task error: boom", task.ErrorMessage)
+	}
+	engine.AssertExternalTaskBPMNError(t, rejected.Id, "rejected")
+
+	// all handlers share one fetch loop
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches.maxInFlight))
+}
+
+// fetchCounter counts concurrent fetch-and-lock requests
+type fetchCounter struct {
+	next        http.RoundTripper
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (c *fetchCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/fetchAndLock") {
+		return c.next.RoundTrip(req)
+	}
+
+	inFlight := atomic.AddInt32(&c.inFlight, 1)
+	defer atomic.AddInt32(&c.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&c.maxInFlight)
+		if inFlight <= max || atomic.CompareAndSwapInt32(&c.maxInFlight, max, inFlight) {
+			break
+		}
+	}
+
+	return c.next.RoundTrip(req)
+}
+
+func TestProcessorBackpressure(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	for i := 0; i < 3; i++ {
+		engine.AddExternalTask("slow", nil)
+	}
+
+	var mu sync.Mutex
+	var requested []int
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:                  "test-worker",
+		LockDuration:              time.Minute,
+		MaxTasks:                  10,
+		MaxParallelTaskPerHandler: 1,
+		LongPollingTimeout:        time.Second,
+		Hooks: &processor.Hooks{
+			OnFetchStarted: func(event processor.FetchEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				requested = append(requested, event.MaxTasks)
+			},
+		},
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "slow"}}, func(ctx *processor.Context) error {
+		started <- struct{}{}
+		<-release
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	<-started
+	close(release)
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		for _, task := range engine.ExternalTasks() {
+			if task.State != camundatest.ExternalTaskCompleted {
+				return false
+			}
+		}
+		return true
+	}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, maxTasks := range requested {
+		assert.Equal(t, 1, maxTasks, "only tasks of free workers are fetched")
+	}
+}
+
+func TestProcessorFetchLoopsReserveWorkers(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	for i := 0; i < 6; i++ {
+		engine.AddExternalTask("slow", nil)
+	}
+
+	var mu sync.Mutex
+	pending, maxPending, unlocked := 0, 0, 0
+	started := make(chan struct{}, 6)
+	release := make(chan struct{})
+	client := engine.Client()
+	client.SetCustomTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if strings.HasSuffix(req.URL.Path, "/unlock") {
+			mu.Lock()
+			unlocked++
+			mu.Unlock()
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	}))
+	proc := processor.NewProcessor(client, &processor.Options{
+		WorkerId:                  "test-worker",
+		LockDuration:              time.Minute,
+		FetchLoops:                3,
+		MaxParallelTaskPerHandler: 2,
+		LongPollingTimeout:        time.Second,
+		Hooks: &processor.Hooks{
+			OnFetchStarted: func(event processor.FetchEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				pending += event.MaxTasks
+				if pending > maxPending {
+					maxPending = pending
+				}
+			},
+			OnFetchFinished: func(event processor.FetchEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				pending -= event.MaxTasks
+			},
+		},
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "slow"}}, func(ctx *processor.Context) error {
+		started <- struct{}{}
+		<-release
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	<-started
+	<-started
+	close(release)
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		for _, task := range engine.ExternalTasks() {
+			if task.State != camundatest.ExternalTaskCompleted {
+				return false
+			}
+		}
+		return true
+	}))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, maxPending, 2, "fetch loops request tasks of different workers")
+	assert.Equal(t, 0, unlocked, "no task is fetched without a free worker")
+}
+
+func TestProcessorRefetchesForFreedWorker(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	first := engine.AddExternalTask("busy", nil)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:                  "test-worker",
+		LockDuration:              time.Minute,
+		MaxParallelTaskPerHandler: 1,
+		LongPollingTimeout:        time.Minute,
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "busy"}}, func(ctx *processor.Context) error {
+		started <- struct{}{}
+		<-release
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	// the fetch loop long polls the idle topic while the busy handler has no free worker
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "idle"}}, func(ctx *processor.Context) error {
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	<-started
+	second := engine.AddExternalTask("busy", nil)
+	close(release)
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		firstTask, _ := engine.ExternalTask(first.Id)
+		secondTask, _ := engine.ExternalTask(second.Id)
+		return firstTask.State == camundatest.ExternalTaskCompleted && secondTask.State == camundatest.ExternalTaskCompleted
+	}), "the task of the freed worker is fetched before the long polling timeout")
+}
+
+func TestProcessorHandlerOptions(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	image := engine.AddExternalTask("resize-image", nil)
+	notification := engine.AddExternalTask("notify", nil)
+
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:                  "test-worker",
+		LockDuration:              10 * time.Second,
+		MaxParallelTaskPerHandler: 200,
+		MaxParallelTasks:          50,
+		LongPollingTimeout:        time.Second,
+	}, nil)
+	release := make(chan struct{})
+	defer func() {
+		close(release)
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+	usePriority := true
+	proc.AddHandlerWithOptions([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "resize-image"}}, func(ctx *processor.Context) error {
+		<-release
+		return nil
+	}, processor.HandlerOptions{LockDuration: 10 * time.Minute, MaxParallelTasks: 2, UsePriority: &usePriority})
+	proc.AddHandlerWithOptions([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "notify"}}, func(ctx *processor.Context) error {
+		<-release
+		return nil
+	}, processor.HandlerOptions{Weight: 3})
+
+	locked := func(id string) bool {
+		task, _ := engine.ExternalTask(id)
+		return task.Locked(time.Now())
+	}
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		return locked(image.Id) && locked(notification.Id)
+	}))
+
+	imageTask, _ := engine.ExternalTask(image.Id)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), imageTask.LockExpirationTime, 5*time.Second)
+	notificationTask, _ := engine.ExternalTask(notification.Id)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), notificationTask.LockExpirationTime, 5*time.Second)
+}
+
+func TestProcessorSubscription(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+
+	var mu sync.Mutex
+	var fetchedTopics []string
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:           "test-worker",
+		LockDuration:       time.Minute,
+		LongPollingTimeout: 100 * time.Millisecond,
+		Hooks: &processor.Hooks{
+			OnFetchStarted: func(event processor.FetchEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				fetchedTopics = append(fetchedTopics, event.Topics...)
+			},
+		},
+	}, nil)
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+	started, release := make(chan struct{}), make(chan struct{})
+	subscription := proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "charge"}}, func(ctx *processor.Context) error {
+		if ctx.Task.Variables["block"].Value == true {
+			close(started)
+			<-release
+		}
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	assert.Equal(t, []string{"charge"}, subscription.Topics())
+	// tasks of a handler fetched by the same fetch loop show that requests issued after a change are finished
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "ping"}}, func(ctx *processor.Context) error {
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	completed := func(id string) func() bool {
+		return func() bool {
+			task, _ := engine.ExternalTask(id)
+			return task.State == camundatest.ExternalTaskCompleted
+		}
+	}
+	ping := func() {
+		require.True(t, engine.WaitFor(t, 5*time.Second, completed(engine.AddExternalTask("ping", nil).Id)))
+	}
+	fetchedSince := func(action func()) bool {
+		mu.Lock()
+		fetchedTopics = nil
+		mu.Unlock()
+		action()
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, topic := range fetchedTopics {
+			if topic == "charge" {
+				return true
+			}
+		}
+		return false
+	}
+
+	subscription.Pause()
+	assert.True(t, subscription.Paused())
+	ping()
+	var paused camundatest.ExternalTask
+	assert.False(t, fetchedSince(func() {
+		paused = engine.AddExternalTask("charge", nil)
+		ping()
+	}), "topics of the paused handler are not fetched")
+	task, _ := engine.ExternalTask(paused.Id)
+	assert.Equal(t, camundatest.ExternalTaskActive, task.State)
+	assert.False(t, task.Locked(time.Now()))
+
+	subscription.Resume()
+	require.True(t, engine.WaitFor(t, 5*time.Second, completed(paused.Id)))
+
+	blocked := engine.AddExternalTask("charge", map[string]camundaclientgo.Variable{"block": {Value: true, Type: "Boolean"}})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task is not handled")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, subscription.Remove(ctx), "the in-flight task is not drained")
+
+	close(release)
+	require.NoError(t, subscription.Remove(context.Background()))
+	engine.AssertExternalTaskCompleted(t, blocked.Id)
+
+	ping()
+	var removed camundatest.ExternalTask
+	assert.False(t, fetchedSince(func() {
+		removed = engine.AddExternalTask("charge", nil)
+		ping()
+	}), "topics of the removed handler are not fetched")
+	task, _ = engine.ExternalTask(removed.Id)
+	assert.False(t, task.Locked(time.Now()))
+}
+
+func TestProcessorHooks(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	completed := engine.AddExternalTask("hooks", nil)
+
+	var mu sync.Mutex
+	var events []string
+	record := func(name string) func(processor.TaskEvent) {
+		return func(event processor.TaskEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, name+" "+event.Task.Id)
+		}
+	}
+	var fetched int32
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:           "test-worker",
+		LockDuration:       time.Minute,
+		LongPollingTimeout: 100 * time.Millisecond,
+		Hooks: &processor.Hooks{
+			OnFetchFinished: func(event processor.FetchEvent) {
+				atomic.AddInt32(&fetched, int32(event.Count()))
+			},
+			OnTaskDispatched: record("dispatched"),
+			OnHandlerStarted: record("started"),
+			OnLockExtended:   record("extended"),
+			OnTaskCompleted:  record("completed"),
+		},
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "hooks"}}, func(ctx *processor.Context) error {
+		if err := ctx.ExtendLock(2 * time.Minute); err != nil {
+			return err
+		}
+		return ctx.Complete(processor.QueryComplete{})
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	require.True(t, engine.WaitFor(t, 5*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 4
+	}))
+	id := completed.Id
+	assert.Equal(t, []string{"dispatched " + id, "started " + id, "extended " + id, "completed " + id}, events)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+}
+
+func TestProcessorExtendLockMovesDeadline(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	task := engine.AddExternalTask("extend", nil)
+
+	var lost int32
+	completeErr := make(chan error, 1)
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:           "test-worker",
+		LockDuration:       time.Second,
+		LongPollingTimeout: 100 * time.Millisecond,
+		Hooks: &processor.Hooks{
+			OnLockLost: func(processor.TaskEvent) {
+				atomic.AddInt32(&lost, 1)
+			},
+		},
+	}, nil)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "extend"}}, func(ctx *processor.Context) error {
+		if err := ctx.ExtendLock(time.Minute); err != nil {
+			return err
+		}
+		// the handler runs past the expiration of the first lock
+		time.Sleep(1500 * time.Millisecond)
+		err := ctx.Complete(processor.QueryComplete{})
+		completeErr <- err
+		return err
+	})
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+
+	select {
+	case err := <-completeErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler did not complete the task")
+	}
+	engine.AssertExternalTaskCompleted(t, task.Id)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&lost))
+	assert.Equal(t, 0, engine.ExternalTasks()[0].Failures)
+}
+
+func TestProcessorValidateTopics(t *testing.T) {
+	engine := camundatest.NewEngine()
+	defer engine.Close()
+	_, err := engine.Deploy("order.bpmn", []byte(orderProcess))
+	require.NoError(t, err)
+	_, err = engine.Deploy("check.bpmn", []byte(escalationProcess))
+	require.NoError(t, err)
+
+	proc := processor.NewProcessor(engine.Client(), &processor.Options{
+		WorkerId:           "test-worker",
+		LockDuration:       time.Minute,
+		LongPollingTimeout: 100 * time.Millisecond,
+	}, nil)
+	defer func() {
+		assert.NoError(t, proc.Shutdown(context.Background()))
+	}()
+	handler := func(ctx *processor.Context) error { return nil }
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "charge"}, {TopicName: "verify"}}, handler)
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "refund"}}, handler)
+
+	err = proc.ValidateTopics(nil)
+	var mismatch *processor.TopicMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, map[string][]string{"reserve": {"order"}}, mismatch.Unhandled)
+	assert.Equal(t, []string{"refund"}, mismatch.Unused)
+	assert.EqualError(t, err, "topics mismatch: topic reserve of order has no handler; topic refund of a handler is not used by deployed processes")
+
+	assert.Error(t, proc.ValidateTopics(map[string]string{"key": "check"}), "topics of other processes are unused")
+
+	proc.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{{TopicName: "reserve"}}, handler)
+	topics, err := processor.DeployedTopics(engine.Client(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"charge": {"order"}, "reserve": {"order"}, "verify": {"check"}}, topics)
+}
+
+const orderProcess = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:camunda="http://camunda.org/schema/1.0/bpmn" id="Definitions_1">
+  <bpmn:process id="order" name="Order" isExecutable="true">
+    <bpmn:startEvent id="start" />
+    <bpmn:sequenceFlow id="f1" sourceRef="start" targetRef="fork" />
+    <bpmn:parallelGateway id="fork" />
+    <bpmn:sequenceFlow id="f2" sourceRef="fork" targetRef="charge" />
+    <bpmn:sequenceFlow id="f3" sourceRef="fork" targetRef="reserve" />
+    <bpmn:serviceTask id="charge" camunda:type="external" camunda:topic="charge" />
+    <bpmn:serviceTask id="reserve" camunda:type="external" camunda:topic="reserve" />
+    <bpmn:boundaryEvent id="declined" attachedToRef="charge">
+      <bpmn:errorEventDefinition errorRef="Error_declined" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f4" sourceRef="charge" targetRef="paid" />
+    <bpmn:sequenceFlow id="f5" sourceRef="reserve" targetRef="join" />
+    <bpmn:sequenceFlow id="f6" sourceRef="declined" targetRef="review" />
+    <bpmn:userTask id="review" name="Review payment" camunda:assignee="demo" />
+    <bpmn:sequenceFlow id="f7" sourceRef="review" targetRef="paid" />
+    <bpmn:exclusiveGateway id="paid" />
+    <bpmn:sequenceFlow id="f10" sourceRef="paid" targetRef="join" />
+    <bpmn:parallelGateway id="join" />
+    <bpmn:sequenceFlow id="f8" sourceRef="join" targetRef="shipped" />
+    <bpmn:intermediateCatchEvent id="shipped">
+      <bpmn:messageEventDefinition messageRef="Message_shipped" />
+    </bpmn:intermediateCatchEvent>
+    <bpmn:sequenceFlow id="f9" sourceRef="shipped" targetRef="end" />
+    <bpmn:endEvent id="end" />
+  </bpmn:process>
+  <bpmn:error id="Error_declined" name="Declined" errorCode="payment-declined" />
+  <bpmn:message id="Message_shipped" name="OrderShipped" />
+</bpmn:definitions>`
+
+const escalationProcess = `<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:camunda="http://camunda.org/schema/1.0/bpmn" id="Definitions_1">
+  <bpmn:process id="check" isExecutable="true">
+    <bpmn:startEvent id="start" />
+    <bpmn:sequenceFlow id="f1" sourceRef="start" targetRef="verify" />
+    <bpmn:serviceTask id="verify" camunda:type="external" camunda:topic="verify" />
+    <bpmn:sequenceFlow id="f2" sourceRef="verify" targetRef="end" />
+    <bpmn:boundaryEvent id="slow" attachedToRef="verify" cancelActivity="false">
+      <bpmn:escalationEventDefinition escalationRef="Escalation_slow" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f3" sourceRef="slow" targetRef="inform" />
+    <bpmn:userTask id="inform" />
+    <bpmn:sequenceFlow id="f4" sourceRef="inform" targetRef="end" />
+    <bpmn:boundaryEvent id="manual" attachedToRef="verify">
+      <bpmn:escalationEventDefinition escalationRef="Escalation_manual" />
+    </bpmn:boundaryEvent>
+    <bpmn:sequenceFlow id="f5" sourceRef="manual" targetRef="review" />
+    <bpmn:userTask id="review" />
+    <bpmn:sequenceFlow id="f6" sourceRef="review" targetRef="end" />
+    <bpmn:endEvent id="end" />
+  </bpmn:process>
+  <bpmn:escalation id="Escalation_slow" escalationCode="slow" />
+  <bpmn:escalation id="Escalation_manual" escalationCode="manual" />
+</bpmn:definitions>`
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,223 @@
+// Package processortest provides utilities to unit test handlers of external tasks without an engine.
+//
+//	task := processortest.NewTask("send-invoice", map[string]camundaclientgo.Variable{
+//		"amount": {Value: 42, Type: "Integer"},
+//	})
+//	task.BusinessKey = "order-1"
+//
+//	result := processortest.Run(handler, task)
+//	if result.Outcome != processor.OutcomeComplete {
+//		t.Fatalf("task is not completed: %s", result.Err)
+//	}
+package processortest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+	"github.com/wurenquyu/camunda-client-go/v3/processor"
+)
+
+const (
+	// TaskId an id of tasks created by NewTask
+	TaskId = "test-task"
+	// WorkerId a worker id of tasks created by NewTask
+	WorkerId = "test-worker"
+	// ProcessInstanceId a process instance id of tasks created by NewTask
+	ProcessInstanceId = "test-process-instance"
+)
+
+// NewTask returns a locked task of the topic with the variables. Set other fields, e.g. BusinessKey or Retries,
+// on the returned task
+func NewTask(topic string, variables map[string]camundaclientgo.Variable) *camundaclientgo.ResLockedExternalTask {
+	if variables == nil {
+		variables = map[string]camundaclientgo.Variable{}
+	}
+
+	return &camundaclientgo.ResLockedExternalTask{
+		Id:                  TaskId,
+		TopicName:           topic,
+		ActivityId:          topic,
+		ActivityInstanceId:  topic + ":" + TaskId,
+		ExecutionId:         ProcessInstanceId,
+		ProcessInstanceId:   ProcessInstanceId,
+		ProcessDefinitionId: "test-process:1:test-process-definition",
+		LockExpirationTime:  time.Now().Add(time.Minute).Format(camundaclientgo.DefaultDateTimeFormat),
+		WorkerId:            WorkerId,
+		Variables:           variables,
+	}
+}
+
+// CallType a type of a report to the engine
+type CallType string
+
+const (
+	CallComplete         CallType = "complete"
+	CallHandleBPMNError  CallType = "bpmnError"
+	CallHandleEscalation CallType = "escalation"
+	CallHandleFailure    CallType = "failure"
+	CallExtendLock       CallType = "extendLock"
+)
+
+// Call a report of a handler to the engine, only the query of the type is set
+type Call struct {
+	Type       CallType
+	TaskId     string
+	Complete   *camundaclientgo.QueryComplete
+	BPMNError  *camundaclientgo.QueryHandleBPMNError
+	Escalation *camundaclientgo.QueryHandleEscalation
+	Failure    *camundaclientgo.QueryHandleFailure
+	ExtendLock *camundaclientgo.QueryExtendLock
+	// Err an error returned to the handler
+	Err error
+}
+
+// Client a fake processor.ExternalTaskClient which records reports of handlers
+type Client struct {
+	// Err is returned by all reports if it is set, e.g. to test handling of engine errors
+	Err error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Complete records the completion of the task
+func (c *Client) Complete(id string, query camundaclientgo.QueryComplete) error {
+	return c.record(Call{Type: CallComplete, TaskId: id, Complete: &query})
+}
+
+// HandleBPMNError records the BPMN error of the task
+func (c *Client) HandleBPMNError(id string, query camundaclientgo.QueryHandleBPMNError) error {
+	return c.record(Call{Type: CallHandleBPMNError, TaskId: id, BPMNError: &query})
+}
+
+// HandleEscalation records the escalation of the task
+func (c *Client) HandleEscalation(id string, query camundaclientgo.QueryHandleEscalation) error {
+	return c.record(Call{Type: CallHandleEscalation, TaskId: id, Escalation: &query})
+}
+
+// HandleFailure records the failure of the task
+func (c *Client) HandleFailure(id string, query camundaclientgo.QueryHandleFailure) error {
+	return c.record(Call{Type: CallHandleFailure, TaskId: id, Failure: &query})
+}
+
+// ExtendLock records the lock extension of the task
+func (c *Client) ExtendLock(id string, query camundaclientgo.QueryExtendLock) error {
+	return c.record(Call{Type: CallExtendLock, TaskId: id, ExtendLock: &query})
+}
+
+func (c *Client) record(call Call) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call.Err = c.Err
+	c.calls = append(c.calls, call)
+
+	return c.Err
+}
+
+// Calls returns all reports in the order of calls
+func (c *Client) Calls() []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Call{}, c.calls...)
+}
+
+// Result a result of a handler run
+type Result struct {
+	// Outcome the result reported to the engine
+	Outcome processor.Outcome
+	// Err an error returned by the handler or a panic of the handler
+	Err error
+	// Complete the query of the completion if the task is completed
+	Complete *camundaclientgo.QueryComplete
+	// BPMNError the query of the BPMN error if it is reported
+	BPMNError *camundaclientgo.QueryHandleBPMNError
+	// Escalation the query of the escalation if it is reported
+	Escalation *camundaclientgo.QueryHandleEscalation
+	// Failure the query of the failure if it is reported, e.g. with retries and retry timeout
+	Failure *camundaclientgo.QueryHandleFailure
+	// Calls all reports of the task including failed ones
+	Calls []Call
+}
+
+// Variables returns variables of the completion, the BPMN error or the escalation
+func (r *Result) Variables() map[string]camundaclientgo.Variable {
+	switch {
+	case r.Complete != nil && r.Complete.Variables != nil:
+		return *r.Complete.Variables
+	case r.BPMNError != nil && r.BPMNError.Variables != nil:
+		return *r.BPMNError.Variables
+	case r.Escalation != nil && r.Escalation.Variables != nil:
+		return *r.Escalation.Variables
+	}
+
+	return nil
+}
+
+// ErrorCode returns the code of the reported BPMN error
+func (r *Result) ErrorCode() string {
+	if r.BPMNError == nil || r.BPMNError.ErrorCode == nil {
+		return ""
+	}
+
+	return *r.BPMNError.ErrorCode
+}
+
+// Run runs the handler for the task like the processor does and returns the result reported to a fake engine
+func Run(handler processor.Handler, task *camundaclientgo.ResLockedExternalTask) *Result {
+	return (&Harness{}).Run(handler, task)
+}
+
+// Harness runs handlers with a configured fake engine
+type Harness struct {
+	// Context a context of the handler (default: context.Background())
+	Context context.Context
+	// Client receives reports of the handler, e.g. a Client which fails reports (default: a new Client)
+	Client *Client
+	// RetryPolicy a retry policy of failures (default: retries are not set)
+	RetryPolicy *processor.RetryPolicy
+}
+
+// Run runs the handler for the task and returns the result reported to the client of the harness
+func (h *Harness) Run(handler processor.Handler, task *camundaclientgo.ResLockedExternalTask) *Result {
+	ctx := h.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := h.Client
+	if client == nil {
+		client = &Client{}
+	}
+
+	first := len(client.Calls())
+	handlerCtx := processor.NewContext(ctx, task, client)
+	err := processor.Run(handlerCtx, handler, h.RetryPolicy)
+
+	result := &Result{
+		Outcome: handlerCtx.Outcome(),
+		Err:     err,
+		Calls:   client.Calls()[first:],
+	}
+	for _, call := range result.Calls {
+		if call.Err != nil {
+			continue
+		}
+
+		switch call.Type {
+		case CallComplete:
+			result.Complete = call.Complete
+		case CallHandleBPMNError:
+			result.BPMNError = call.BPMNError
+		case CallHandleEscalation:
+			result.Escalation = call.Escalation
+		case CallHandleFailure:
+			result.Failure = call.Failure
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,78 @@
+package processortest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+	"github.com/wurenquyu/camunda-client-go/v3/processor"
+)
+
+func invoiceHandler(ctx *processor.Context) error {
+	amount, ok := ctx.Task.Variables["amount"].Value.(int)
+	if !ok {
+		return errors.New("amount is missing")
+	}
+
+	if amount > 100 {
+		code, message := "limit-exceeded", "amount exceeds the limit"
+		return ctx.HandleBPMNError(processor.QueryHandleBPMNError{
+			ErrorCode:    &code,
+			ErrorMessage: &message,
+			Variables:    &map[string]camundaclientgo.Variable{"limit": {Value: 100, Type: "Integer"}},
+		})
+	}
+
+	if ctx.Task.BusinessKey == "panic" {
+		panic("unexpected business key")
+	}
+
+	return ctx.Complete(processor.QueryComplete{
+		Variables: &map[string]camundaclientgo.Variable{"invoice": {Value: ctx.Task.BusinessKey, Type: "String"}},
+	})
+}
+
+func TestRun(t *testing.T) {
+	task := NewTask("send-invoice", map[string]camundaclientgo.Variable{"amount": {Value: 42, Type: "Integer"}})
+	task.BusinessKey = "order-1"
+
+	result := Run(invoiceHandler, task)
+	require.NoError(t, result.Err)
+	assert.Equal(t, processor.OutcomeComplete, result.Outcome)
+	assert.Equal(t, "order-1", result.Variables()["invoice"].Value)
+	require.Len(t, result.Calls, 1)
+	assert.Equal(t, TaskId, result.Calls[0].TaskId)
+	assert.Equal(t, WorkerId, *result.Complete.WorkerId)
+
+	result = Run(invoiceHandler, NewTask("send-invoice", map[string]camundaclientgo.Variable{"amount": {Value: 500, Type: "Integer"}}))
+	assert.Equal(t, processor.OutcomeBPMNError, result.Outcome)
+	assert.Equal(t, "limit-exceeded", result.ErrorCode())
+	assert.Equal(t, 100, result.Variables()["limit"].Value)
+
+	result = Run(invoiceHandler, NewTask("send-invoice", nil))
+	assert.EqualError(t, result.Err, "amount is missing")
+	assert.Equal(t, processor.OutcomeFailure, result.Outcome)
+	assert.Equal(t, "task error: amount is missing", *result.Failure.ErrorMessage)
+
+	task.BusinessKey = "panic"
+	result = Run(invoiceHandler, task)
+	assert.Equal(t, processor.OutcomePanic, result.Outcome)
+	assert.Contains(t, *result.Failure.ErrorDetails, "unexpected business key")
+}
+
+func TestRunWithFailingClient(t *testing.T) {
+	client := &Client{Err: errors.New("engine is unavailable")}
+	task := NewTask("send-invoice", map[string]camundaclientgo.Variable{"amount": {Value: 42, Type: "Integer"}})
+
+	result := (&Harness{Context: context.Background(), Client: client}).Run(invoiceHandler, task)
+	assert.EqualError(t, result.Err, "engine is unavailable")
+	assert.Equal(t, processor.OutcomeNone, result.Outcome)
+	assert.Nil(t, result.Complete)
+	assert.Nil(t, result.Failure)
+	require.Len(t, result.Calls, 2)
+	assert.Equal(t, CallComplete, result.Calls[0].Type)
+	assert.Equal(t, CallHandleFailure, result.Calls[1].Type)
+}
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// RetryPolicy retries of a task after its handler returned an error.
+// Retries are counted by the engine: the first failure sets MaxRetries, every next failure decrements
+// the retries of the task and the engine creates an incident when no retries are left
+type RetryPolicy struct {
+	// MaxRetries retries of a task after the first failure, 0 creates an incident on the first failure
+	MaxRetries int
+	// RetryTimeout a timeout before the first retry
+	RetryTimeout time.Duration
+	// Multiplier of the timeout for every next retry, 0 or 1 keeps the timeout fixed
+	Multiplier float64
+	// MaxRetryTimeout a maximum timeout between retries (default: unlimited)
+	MaxRetryTimeout time.Duration
+}
+
+// retries returns retries left and the timeout before the next retry of the task after a failure
+func (p *RetryPolicy) retries(task *camundaclientgo.ResLockedExternalTask) (int, time.Duration) {
+	retries := p.MaxRetries
+	if task.Retries != nil {
+		retries = *task.Retries - 1
+	}
+	if retries <= 0 {
+		return 0, 0
+	}
+	if retries > p.MaxRetries {
+		retries = p.MaxRetries
+	}
+
+	timeout := p.RetryTimeout
+	if p.Multiplier > 1 {
+		attempt := p.MaxRetries - retries
+		timeout = time.Duration(float64(timeout) * math.Pow(p.Multiplier, float64(attempt)))
+	}
+	if p.MaxRetryTimeout > 0 && (timeout > p.MaxRetryTimeout || timeout < 0) {
+		timeout = p.MaxRetryTimeout
+	}
+
+	return retries, timeout
+}
+
+// FailureError an error of a handler which overrides the retry policy of the failure reported to the engine
+type FailureError struct {
+	Err error
+	// Details error details of the failure, e.g. a stack trace or a response of a service
+	Details string
+	// Retries retries left, 0 creates an incident (default: by the retry policy)
+	Retries *int
+	// RetryTimeout a timeout before the next retry (default: by the retry policy)
+	RetryTimeout *time.Duration
+}
+
+// Error returns the message of the error
+func (e *FailureError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *FailureError) Unwrap() error {
+	return e.Err
+}
+
+// Retry returns an error which reports a failure with the retries left and the timeout before the next retry
+func Retry(err error, retries int, retryTimeout time.Duration) error {
+	return &FailureError{Err: err, Retries: &retries, RetryTimeout: &retryTimeout}
+}
+
+// Incident returns a non-retryable error, the engine creates an incident with the details immediately
+func Incident(err error, details string) error {
+	retries := 0
+	return &FailureError{Err: err, Details: details, Retries: &retries}
+}
+
+// failureQuery returns a failure of the task for the error of its handler
+func failureQuery(task *camundaclientgo.ResLockedExternalTask, err error, policy *RetryPolicy) QueryHandleFailure {
+	var query QueryHandleFailure
+	if policy != nil {
+		retries, timeout := policy.retries(task)
+		timeoutMs := int(timeout / time.Millisecond)
+		query.Retries = &retries
+		query.RetryTimeout = &timeoutMs
+	}
+
+	var failureErr *FailureError
+	if !errors.As(err, &failureErr) {
+		return query
+	}
+
+	if failureErr.Details != "" {
+		query.ErrorDetails = &failureErr.Details
+	}
+	if failureErr.Retries != nil {
+		retries := *failureErr.Retries
+		if retries < 0 {
+			retries = 0
+		}
+		query.Retries = &retries
+	}
+	if failureErr.RetryTimeout != nil {
+		timeoutMs := int(*failureErr.RetryTimeout / time.Millisecond)
+		query.RetryTimeout = &timeoutMs
+	}
+
+	return query
+}
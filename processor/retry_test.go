@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxRetries:      4,
+		RetryTimeout:    time.Second,
+		Multiplier:      2,
+		MaxRetryTimeout: 5 * time.Second,
+	}
+
+	tests := []struct {
+		taskRetries *int
+		retries     int
+		timeout     time.Duration
+	}{
+		{nil, 4, time.Second},
+		{intPtr(4), 3, 2 * time.Second},
+		{intPtr(3), 2, 4 * time.Second},
+		{intPtr(2), 1, 5 * time.Second},
+		{intPtr(1), 0, 0},
+		{intPtr(10), 4, time.Second},
+	}
+	for _, test := range tests {
+		retries, timeout := policy.retries(&camundaclientgo.ResLockedExternalTask{Retries: test.taskRetries})
+		assert.Equal(t, test.retries, retries)
+		assert.Equal(t, test.timeout, timeout)
+	}
+
+	fixed := &RetryPolicy{MaxRetries: 3, RetryTimeout: time.Minute}
+	retries, timeout := fixed.retries(&camundaclientgo.ResLockedExternalTask{Retries: intPtr(2)})
+	assert.Equal(t, 1, retries)
+	assert.Equal(t, time.Minute, timeout)
+}
+
+func TestFailureQuery(t *testing.T) {
+	task := &camundaclientgo.ResLockedExternalTask{Retries: intPtr(3)}
+	policy := &RetryPolicy{MaxRetries: 5, RetryTimeout: time.Second}
+
+	query := failureQuery(task, errors.New("failed"), nil)
+	assert.Nil(t, query.Retries)
+	assert.Nil(t, query.RetryTimeout)
+
+	query = failureQuery(task, errors.New("failed"), policy)
+	assert.Equal(t, 2, *query.Retries)
+	assert.Equal(t, 1000, *query.RetryTimeout)
+
+	query = failureQuery(task, fmt.Errorf("wrapped: %w", Retry(errors.New("failed"), 10, time.Minute)), policy)
+	assert.Equal(t, 10, *query.Retries)
+	assert.Equal(t, 60000, *query.RetryTimeout)
+	assert.Nil(t, query.ErrorDetails)
+
+	query = failureQuery(task, Incident(errors.New("invalid iban"), "iban DE00 is invalid"), policy)
+	assert.Equal(t, 0, *query.Retries)
+	assert.Equal(t, "iban DE00 is invalid", *query.ErrorDetails)
+
+	query = failureQuery(task, Incident(errors.New("invalid iban"), ""), nil)
+	assert.Equal(t, 0, *query.Retries)
+	assert.Nil(t, query.RetryTimeout)
+}
+
+func TestRunWithRetryPolicy(t *testing.T) {
+	client := &fakeClient{}
+	ctx := newTestContext(client)
+
+	err := Run(ctx, func(*Context) error {
+		return Incident(errors.New("invalid iban"), "details")
+	}, &RetryPolicy{MaxRetries: 3})
+
+	assert.EqualError(t, err, "invalid iban")
+	assert.Equal(t, OutcomeFailure, ctx.Outcome())
+	assert.Equal(t, "task error: invalid iban", *client.failures[0].ErrorMessage)
+	assert.Equal(t, 0, *client.failures[0].Retries)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
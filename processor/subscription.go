@@ -0,0 +1,124 @@
+package processor
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Subscription a handler registered by AddHandler, it pauses, resumes and removes the handler at runtime
+type Subscription struct {
+	p      *Processor
+	pool   *handlerPool
+	group  *fetchGroup
+	topics []string
+}
+
+// Topics returns names of topics handled by the handler
+func (s *Subscription) Topics() []string {
+	return append([]string{}, s.topics...)
+}
+
+// Pause stops fetching tasks of the topics, in-flight tasks are handled and tasks fetched by pending requests
+// are unlocked
+func (s *Subscription) Pause() {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	s.pool.paused = true
+	s.p.notifyLocked()
+}
+
+// Resume continues fetching tasks of the paused topics
+func (s *Subscription) Resume() {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	s.pool.paused = false
+	s.p.notifyLocked()
+}
+
+// Paused returns true if fetching tasks of the topics is paused
+func (s *Subscription) Paused() bool {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	return s.pool.paused
+}
+
+// Remove deregisters the handler and waits until its in-flight tasks are handled or the ctx is done.
+// Workers of the handler are stopped in both cases, they exit after their in-flight tasks and tasks waiting
+// for a worker are unlocked. Topics of the handler can be added again by AddHandler after the call
+func (s *Subscription) Remove(ctx context.Context) error {
+	s.p.mu.Lock()
+	if !s.pool.removed {
+		s.pool.removed = true
+		for _, topic := range s.topics {
+			delete(s.p.pools, topic)
+			delete(s.p.fetchStates, topic)
+		}
+
+		topics := s.group.topics[:0:0]
+		for _, topic := range s.group.topics {
+			if s.p.pools[topic.TopicName] != nil {
+				topics = append(topics, topic)
+			}
+		}
+		s.group.topics = topics
+		s.p.notifyLocked()
+	}
+	s.p.mu.Unlock()
+
+	err := s.p.waitDrained(ctx, &s.pool.reserved)
+	s.pool.close()
+
+	return err
+}
+
+// Shutdown stops fetching tasks of all handlers and waits until in-flight tasks are handled or the ctx is done.
+// Tasks fetched by pending requests are unlocked, contexts of handlers are cancelled if the ctx is done before.
+// Workers are stopped in both cases as by Subscription.Remove. It waits for reports retried by the outbox too,
+// they stay in the outbox for the next run if the ctx is done before. The processor can't be used after the call
+func (p *Processor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.draining {
+		p.draining = true
+		p.cancel()
+		p.notifyLocked()
+	}
+	p.mu.Unlock()
+
+	err := p.waitDrained(ctx, &p.reserved)
+	if err == nil && p.outbox != nil {
+		err = p.outbox.wait(ctx)
+	}
+	if err != nil {
+		p.cancelHandlers()
+	}
+
+	p.mu.Lock()
+	for _, pool := range p.pools {
+		pool.close()
+	}
+	p.mu.Unlock()
+
+	return err
+}
+
+// waitDrained waits until the reserved counter is 0 or the ctx is done
+func (p *Processor) waitDrained(ctx context.Context, reserved *int32) error {
+	for {
+		p.mu.Lock()
+		changed := p.changed
+		p.mu.Unlock()
+
+		if atomic.LoadInt32(reserved) == 0 {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+func TestShutdown(t *testing.T) {
+	var unlocked int32
+	p := newTestProcessor(t, &Options{}, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/unlock") {
+			atomic.AddInt32(&unlocked, 1)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	pool := &handlerPool{capacity: 1, tasks: make(chan *camundaclientgo.ResLockedExternalTask), stopped: make(chan struct{})}
+	p.pools["topic"] = pool
+	shares := map[*handlerPool]int{pool: 3}
+	p.reserve(shares)
+
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "in-flight", TopicName: "topic"}, shares)
+	<-pool.tasks
+	// no worker receives the task
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "pending", TopicName: "topic"}, shares)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Shutdown(ctx))
+	assert.Error(t, p.ctx.Err(), "pending fetch requests are cancelled")
+	assert.Error(t, p.handlersCtx.Err(), "handlers are cancelled")
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&unlocked) == 1
+	}, time.Second, time.Millisecond, "the pending task is unlocked after the timeout")
+
+	worker := make(chan struct{})
+	go func() {
+		defer close(worker)
+		p.runWorker(pool)
+	}()
+	select {
+	case <-worker:
+	case <-time.After(time.Second):
+		t.Fatal("workers are not stopped after the timeout")
+	}
+
+	p.dispatch(&camundaclientgo.ResLockedExternalTask{Id: "fetched", TopicName: "topic"}, shares)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&unlocked))
+
+	p.release(pool, true)
+	p.releaseShares(shares)
+	require.NoError(t, p.Shutdown(context.Background()))
+}
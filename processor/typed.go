@@ -0,0 +1,286 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// VariableTag a struct tag of fields bound to variables, e.g. `camunda:"orderId"`.
+// The default name is the field name with a lower case first letter, `camunda:"-"` skips the field.
+// Output fields support the options `local` (a local variable of the task) and `omitempty`
+const VariableTag = "camunda"
+
+var (
+	contextType = reflect.TypeOf(&Context{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+	camundaTime = reflect.TypeOf(camundaclientgo.Time{})
+)
+
+// boundField a struct field bound to a variable
+type boundField struct {
+	index     int
+	name      string
+	local     bool
+	omitEmpty bool
+}
+
+// boundFields returns exported fields of the struct type bound to variables
+func boundFields(t reflect.Type) []boundField {
+	var fields []boundField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := field.Tag.Get(VariableTag)
+		if tag == "-" {
+			continue
+		}
+
+		options := strings.Split(tag, ",")
+		bound := boundField{index: i, name: options[0]}
+		if bound.name == "" {
+			runes := []rune(field.Name)
+			runes[0] = unicode.ToLower(runes[0])
+			bound.name = string(runes)
+		}
+		for _, option := range options[1:] {
+			switch option {
+			case "local":
+				bound.local = true
+			case "omitempty":
+				bound.omitEmpty = true
+			}
+		}
+
+		fields = append(fields, bound)
+	}
+
+	return fields
+}
+
+// TypedHandler returns a handler which decodes variables of the task into the input struct of fn, runs fn and
+// completes the task with the returned output struct encoded as variables. fn has the signature
+// `func(ctx *Context, in *Input) (*Output, error)` or `func(ctx *Context, in *Input) error`.
+// The task is not completed if fn returns an error or reports a result itself.
+// It returns the names of input variables too, they are fetched for the task
+func TypedHandler(fn interface{}) (Handler, []string, error) {
+	value := reflect.ValueOf(fn)
+	t := value.Type()
+	if t.Kind() != reflect.Func {
+		return nil, nil, fmt.Errorf("typed handler must be a func, got %s", t)
+	}
+	if t.NumIn() != 2 || t.In(0) != contextType || !isStructPointer(t.In(1)) {
+		return nil, nil, fmt.Errorf("typed handler %s must accept *processor.Context and a pointer to a struct", t)
+	}
+
+	hasOutput := false
+	switch {
+	case t.NumOut() == 1 && t.Out(0) == errorType:
+	case t.NumOut() == 2 && isStructPointer(t.Out(0)) && t.Out(1) == errorType:
+		hasOutput = true
+	default:
+		return nil, nil, fmt.Errorf("typed handler %s must return a pointer to a struct and an error or only an error", t)
+	}
+
+	inType := t.In(1).Elem()
+	var names []string
+	for _, field := range boundFields(inType) {
+		names = append(names, field.name)
+	}
+
+	handler := func(ctx *Context) error {
+		in := reflect.New(inType)
+		if err := DecodeVariables(ctx.Task.Variables, in.Interface()); err != nil {
+			return err
+		}
+
+		results := value.Call([]reflect.Value{reflect.ValueOf(ctx), in})
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			return err
+		}
+		if ctx.Outcome() != OutcomeNone {
+			return nil
+		}
+
+		query := QueryComplete{}
+		if hasOutput && !results[0].IsNil() {
+			variables, localVariables, err := EncodeVariables(results[0].Interface())
+			if err != nil {
+				return err
+			}
+			if len(variables) > 0 {
+				query.Variables = &variables
+			}
+			if len(localVariables) > 0 {
+				query.LocalVariables = &localVariables
+			}
+		}
+
+		return ctx.Complete(query)
+	}
+
+	return handler, names, nil
+}
+
+// AddTypedHandler adds a typed handler for the topic, see TypedHandler.
+// If the topic has no Variables, only input variables of the handler are fetched
+func (p *Processor) AddTypedHandler(topic *camundaclientgo.QueryFetchAndLockTopic, fn interface{}, middlewares ...Middleware) (*Subscription, error) {
+	handler, names, err := TypedHandler(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if topic.Variables == nil {
+		topic.Variables = append([]string{}, names...)
+	}
+
+	return p.AddHandler([]*camundaclientgo.QueryFetchAndLockTopic{topic}, handler, middlewares...), nil
+}
+
+// DecodeVariables decodes the variables into fields of the struct v points to, fields of missing variables
+// are not changed. Json variables are unmarshalled from their serialized value, Date variables are parsed
+// into time.Time and camunda_client_go.Time fields
+func DecodeVariables(variables map[string]camundaclientgo.Variable, v interface{}) error {
+	value := reflect.ValueOf(v)
+	if !isStructPointer(value.Type()) || value.IsNil() {
+		return fmt.Errorf("can't decode variables into %T, a pointer to a struct is expected", v)
+	}
+
+	value = value.Elem()
+	for _, field := range boundFields(value.Type()) {
+		variable, ok := variables[field.name]
+		if !ok || variable.Value == nil {
+			continue
+		}
+
+		if err := decodeVariable(variable, value.Field(field.index)); err != nil {
+			return fmt.Errorf("can't decode variable %s: %w", field.name, err)
+		}
+	}
+
+	return nil
+}
+
+func decodeVariable(variable camundaclientgo.Variable, field reflect.Value) error {
+	target := field
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+
+	if s, ok := variable.Value.(string); ok {
+		switch {
+		case target.Type() == timeType:
+			parsed, err := time.Parse(camundaclientgo.DefaultDateTimeFormat, s)
+			if err != nil {
+				return err
+			}
+			target.Set(reflect.ValueOf(parsed))
+			return nil
+		case strings.EqualFold(variable.Type, "json") && target.Kind() != reflect.String:
+			return json.Unmarshal([]byte(s), target.Addr().Interface())
+		}
+	}
+
+	data, err := json.Marshal(variable.Value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, target.Addr().Interface())
+}
+
+// EncodeVariables encodes fields of the struct v points to as variables and local variables
+func EncodeVariables(v interface{}) (variables, localVariables map[string]camundaclientgo.Variable, err error) {
+	value := reflect.ValueOf(v)
+	if !isStructPointer(value.Type()) || value.IsNil() {
+		return nil, nil, fmt.Errorf("can't encode %T as variables, a pointer to a struct is expected", v)
+	}
+
+	variables = map[string]camundaclientgo.Variable{}
+	localVariables = map[string]camundaclientgo.Variable{}
+	value = value.Elem()
+	for _, field := range boundFields(value.Type()) {
+		fieldValue := value.Field(field.index)
+		if field.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+
+		variable, err := encodeVariable(fieldValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't encode variable %s: %w", field.name, err)
+		}
+
+		if field.local {
+			localVariables[field.name] = variable
+		} else {
+			variables[field.name] = variable
+		}
+	}
+
+	return variables, localVariables, nil
+}
+
+func encodeVariable(value reflect.Value) (camundaclientgo.Variable, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return camundaclientgo.Variable{Type: "Null"}, nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Type() {
+	case timeType:
+		return camundaclientgo.Variable{
+			Value: value.Interface().(time.Time).Format(camundaclientgo.DefaultDateTimeFormat),
+			Type:  "Date",
+		}, nil
+	case camundaTime:
+		return camundaclientgo.Variable{
+			Value: value.Interface().(camundaclientgo.Time).Format(camundaclientgo.DefaultDateTimeFormat),
+			Type:  "Date",
+		}, nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return camundaclientgo.Variable{Value: value.String(), Type: "String"}, nil
+	case reflect.Bool:
+		return camundaclientgo.Variable{Value: value.Bool(), Type: "Boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return camundaclientgo.Variable{Value: value.Interface(), Type: "Integer"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return camundaclientgo.Variable{Value: value.Interface(), Type: "Long"}, nil
+	case reflect.Float32, reflect.Float64:
+		return camundaclientgo.Variable{Value: value.Float(), Type: "Double"}, nil
+	case reflect.Map, reflect.Slice:
+		if value.IsNil() {
+			return camundaclientgo.Variable{Type: "Null"}, nil
+		}
+		fallthrough
+	case reflect.Struct, reflect.Array:
+		data, err := json.Marshal(value.Interface())
+		if err != nil {
+			return camundaclientgo.Variable{}, err
+		}
+
+		return camundaclientgo.Variable{Value: string(data), Type: "Json"}, nil
+	}
+
+	return camundaclientgo.Variable{}, fmt.Errorf("unsupported type %s", value.Type())
+}
+
+func isStructPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct
+}
@@ -0,0 +1,130 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+type address struct {
+	City string `json:"city"`
+}
+
+type orderInput struct {
+	OrderId  string
+	Amount   float64 `camunda:"amount"`
+	Count    *int
+	Express  bool
+	Created  time.Time
+	Address  address
+	Tags     []string
+	internal string
+	Skipped  string `camunda:"-"`
+}
+
+type orderOutput struct {
+	Invoice   string
+	Total     int64      `camunda:"total"`
+	Shipped   *time.Time `camunda:",omitempty"`
+	Address   address    `camunda:"address,local"`
+	Discount  *float64
+	Unchanged string `camunda:",omitempty"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	handler, names, err := TypedHandler(func(ctx *Context, in *orderInput) (*orderOutput, error) {
+		assert.Equal(t, "order-1", in.OrderId)
+		assert.Equal(t, 12.5, in.Amount)
+		assert.Equal(t, 3, *in.Count)
+		assert.True(t, in.Express)
+		assert.Equal(t, 2021, in.Created.Year())
+		assert.Equal(t, "Berlin", in.Address.City)
+		assert.Equal(t, []string{"a", "b"}, in.Tags)
+		assert.Empty(t, in.Skipped)
+
+		return &orderOutput{
+			Invoice: "INV-" + in.OrderId,
+			Total:   int64(in.Amount * float64(*in.Count)),
+			Address: in.Address,
+		}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orderId", "amount", "count", "express", "created", "address", "tags"}, names)
+
+	client := &recordingClient{}
+	ctx := newTestContext(client)
+	ctx.Task.Variables = map[string]camundaclientgo.Variable{
+		"orderId": {Value: "order-1", Type: "String"},
+		"amount":  {Value: 12.5, Type: "Double"},
+		"count":   {Value: float64(3), Type: "Integer"},
+		"express": {Value: true, Type: "Boolean"},
+		"created": {Value: "2021-03-04T05:06:07.000+0000", Type: "Date"},
+		"address": {Value: `{"city": "Berlin"}`, Type: "Json"},
+		"tags":    {Value: []interface{}{"a", "b"}, Type: "Object"},
+		"Skipped": {Value: "ignored", Type: "String"},
+	}
+
+	require.NoError(t, Run(ctx, handler, nil))
+	assert.Equal(t, OutcomeComplete, ctx.Outcome())
+	require.Len(t, client.completes, 1)
+	assert.Equal(t, map[string]camundaclientgo.Variable{
+		"invoice":  {Value: "INV-order-1", Type: "String"},
+		"total":    {Value: int64(37), Type: "Long"},
+		"discount": {Type: "Null"},
+	}, *client.completes[0].Variables)
+	assert.Equal(t, map[string]camundaclientgo.Variable{
+		"address": {Value: `{"city":"Berlin"}`, Type: "Json"},
+	}, *client.completes[0].LocalVariables)
+}
+
+func TestTypedHandlerErrors(t *testing.T) {
+	handler, _, err := TypedHandler(func(ctx *Context, in *orderInput) error {
+		return NewBPMNError("invalid", "")
+	})
+	require.NoError(t, err)
+
+	client := &recordingClient{}
+	ctx := newTestContext(client)
+	assert.Error(t, Run(ctx, handler, nil))
+	assert.Equal(t, OutcomeBPMNError, ctx.Outcome())
+	assert.Empty(t, client.completes)
+
+	ctx = newTestContext(client)
+	ctx.Task.Variables = map[string]camundaclientgo.Variable{"count": {Value: "three", Type: "String"}}
+	err = Run(ctx, handler, nil)
+	assert.EqualError(t, err, "can't decode variable count: json: cannot unmarshal string into Go value of type int")
+	assert.Equal(t, OutcomeFailure, ctx.Outcome())
+
+	for _, fn := range []interface{}{
+		"handler",
+		func(ctx *Context) error { return nil },
+		func(ctx *Context, in orderInput) error { return nil },
+		func(ctx *Context, in *orderInput) *orderOutput { return nil },
+		func(ctx *Context, in *orderInput) (orderOutput, error) { return orderOutput{}, nil },
+	} {
+		_, _, err := TypedHandler(fn)
+		assert.Error(t, err)
+	}
+}
+
+func TestEncodeVariablesUnsupportedType(t *testing.T) {
+	_, _, err := EncodeVariables(&struct{ Callback func() }{})
+	assert.EqualError(t, err, "can't encode variable callback: unsupported type func()")
+
+	assert.Error(t, DecodeVariables(nil, struct{}{}))
+	assert.NoError(t, DecodeVariables(map[string]camundaclientgo.Variable{}, &orderInput{}))
+}
+
+// recordingClient records completions of handlers
+type recordingClient struct {
+	fakeClient
+	completes []camundaclientgo.QueryComplete
+}
+
+func (c *recordingClient) Complete(_ string, query camundaclientgo.QueryComplete) error {
+	c.completes = append(c.completes, query)
+	return nil
+}
@@ -22,6 +22,15 @@ type QueryHandleBPMNError struct {
 	Variables *map[string]camunda_client_go.Variable `json:"variables"`
 }
 
+// QueryHandleEscalation a query for HandleEscalation request
+type QueryHandleEscalation struct {
+	// An escalation code that indicates the predefined escalation. Is used to identify the BPMN escalation handler
+	EscalationCode *string `json:"escalationCode,omitempty"`
+	// A JSON object containing the variables which will be passed to the execution.
+	// Each key corresponds to a variable name and each value to a variable value
+	Variables *map[string]camunda_client_go.Variable `json:"variables,omitempty"`
+}
+
 // QueryHandleFailure a query for HandleFailure request
 type QueryHandleFailure struct {
 	// An message indicating the reason of the failure
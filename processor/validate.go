@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	camundaclientgo "github.com/wurenquyu/camunda-client-go/v3"
+)
+
+// camundaNamespace the namespace of camunda extension attributes of BPMN
+const camundaNamespace = "http://camunda.org/schema/1.0/bpmn"
+
+// TopicMismatchError mismatches between topics of registered handlers and topics of deployed processes
+type TopicMismatchError struct {
+	// Unhandled keys of process definitions by topics without a handler
+	Unhandled map[string][]string
+	// Unused topics of handlers which no deployed process uses
+	Unused []string
+}
+
+// Error returns a description of all mismatches
+func (e *TopicMismatchError) Error() string {
+	var mismatches []string
+	for _, topic := range sortedKeys(e.Unhandled) {
+		mismatches = append(mismatches, fmt.Sprintf("topic %s of %s has no handler", topic, strings.Join(e.Unhandled[topic], ", ")))
+	}
+	for _, topic := range e.Unused {
+		mismatches = append(mismatches, fmt.Sprintf("topic %s of a handler is not used by deployed processes", topic))
+	}
+
+	return "topics mismatch: " + strings.Join(mismatches, "; ")
+}
+
+// ValidateTopics compares topics of registered handlers with external task topics of the latest versions of deployed
+// process definitions, the query filters process definitions (e.g. "tenantIdIn"). Every mismatch is logged as
+// a warning, a *TopicMismatchError is returned if there are mismatches. Topics set by expressions are skipped.
+// Call it after handlers are added
+func (p *Processor) ValidateTopics(query map[string]string) error {
+	deployed, err := DeployedTopics(p.client, query)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	var registered []string
+	for topic := range p.pools {
+		registered = append(registered, topic)
+	}
+	p.mu.Unlock()
+	sort.Strings(registered)
+
+	mismatch := &TopicMismatchError{Unhandled: map[string][]string{}}
+	for _, topic := range sortedKeys(deployed) {
+		if !containsString(registered, topic) {
+			mismatch.Unhandled[topic] = deployed[topic]
+			p.logger.Warn("topic of deployed processes has no handler", "topic", topic, "processDefinitionKeys", deployed[topic])
+		}
+	}
+	for _, topic := range registered {
+		if _, ok := deployed[topic]; !ok {
+			mismatch.Unused = append(mismatch.Unused, topic)
+			p.logger.Warn("topic of a handler is not used by deployed processes", "topic", topic)
+		}
+	}
+
+	if len(mismatch.Unhandled) == 0 && len(mismatch.Unused) == 0 {
+		return nil
+	}
+
+	return mismatch
+}
+
+// DeployedTopics returns keys of process definitions by external task topics of the latest versions of process
+// definitions, the query filters process definitions
+func DeployedTopics(client *camundaclientgo.Client, query map[string]string) (map[string][]string, error) {
+	listQuery := map[string]string{"latestVersion": "true"}
+	for key, value := range query {
+		listQuery[key] = value
+	}
+
+	definitions, err := client.ProcessDefinition.GetList(listQuery)
+	if err != nil {
+		return nil, fmt.Errorf("can't get process definitions: %w", err)
+	}
+
+	topics := map[string][]string{}
+	for _, definition := range definitions {
+		id := definition.Id
+		res, err := client.ProcessDefinition.GetXML(camundaclientgo.QueryProcessDefinitionBy{Id: &id})
+		if err != nil {
+			return nil, fmt.Errorf("can't get xml of process definition %s: %w", id, err)
+		}
+
+		definitionTopics, err := externalTaskTopics(res.Bpmn20Xml)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse xml of process definition %s: %w", id, err)
+		}
+		for _, topic := range definitionTopics {
+			if !containsString(topics[topic], definition.Key) {
+				topics[topic] = append(topics[topic], definition.Key)
+			}
+		}
+	}
+
+	return topics, nil
+}
+
+// externalTaskTopics returns topics of elements with the camunda:type "external" in the BPMN xml,
+// topics set by expressions are skipped
+func externalTaskTopics(bpmn string) ([]string, error) {
+	var topics []string
+	decoder := xml.NewDecoder(strings.NewReader(bpmn))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var taskType, topic string
+		for _, attr := range element.Attr {
+			if attr.Name.Space != camundaNamespace {
+				continue
+			}
+			switch attr.Name.Local {
+			case "type":
+				taskType = attr.Value
+			case "topic":
+				topic = attr.Value
+			}
+		}
+		if taskType != "external" || topic == "" || strings.Contains(topic, "${") || strings.Contains(topic, "#{") {
+			continue
+		}
+		if !containsString(topics, topic) {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
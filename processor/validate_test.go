@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExternalTaskTopics(t *testing.T) {
+	topics, err := externalTaskTopics(`<?xml version="1.0" encoding="UTF-8"?>
+<bpmn:definitions xmlns:bpmn="http://www.omg.org/spec/BPMN/20100524/MODEL" xmlns:camunda="http://camunda.org/schema/1.0/bpmn">
+  <bpmn:process id="shipping" isExecutable="true">
+    <bpmn:serviceTask id="pack" camunda:type="external" camunda:topic="pack" />
+    <bpmn:serviceTask id="java" camunda:class="org.example.Delegate" />
+    <bpmn:subProcess id="sub">
+      <bpmn:sendTask id="notify" camunda:type="external" camunda:topic="notify" />
+      <bpmn:serviceTask id="again" camunda:type="external" camunda:topic="pack" />
+    </bpmn:subProcess>
+    <bpmn:endEvent id="end">
+      <bpmn:messageEventDefinition camunda:type="external" camunda:topic="${carrier}" />
+    </bpmn:endEvent>
+  </bpmn:process>
+</bpmn:definitions>`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pack", "notify"}, topics)
+
+	_, err = externalTaskTopics("<bpmn:definitions>")
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+// Package prometheus a Prometheus implementation of processor.Metrics
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wurenquyu/camunda-client-go/v3/processor"
+)
+
+// DefaultNamespace a default namespace of metrics
+const DefaultNamespace = "camunda_processor"
+
+// Options options for Metrics
+type Options struct {
+	// namespace of metrics (default: `camunda_processor`)
+	Namespace string
+	// buckets of the handler duration histogram in seconds (default: prometheus.DefBuckets)
+	HandlerDurationBuckets []float64
+	// buckets of the fetch duration histogram in seconds (default: prometheus.DefBuckets)
+	FetchDurationBuckets []float64
+}
+
+// Metrics collects processor metrics as Prometheus metrics labeled by topic
+type Metrics struct {
+	tasksFetched    *prometheus.CounterVec
+	tasksHandled    *prometheus.CounterVec
+	handlerDuration *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	capacity        *prometheus.GaugeVec
+	fetchDuration   *prometheus.HistogramVec
+	fetchErrors     *prometheus.CounterVec
+	emptyPolls      *prometheus.CounterVec
+	backoff         *prometheus.GaugeVec
+}
+
+// NewMetrics a create new instance Metrics and registers it in registerer.
+// If registerer is nil, prometheus.DefaultRegisterer is used
+func NewMetrics(registerer prometheus.Registerer, options Options) (*Metrics, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	if options.Namespace == "" {
+		options.Namespace = DefaultNamespace
+	}
+	if options.HandlerDurationBuckets == nil {
+		options.HandlerDurationBuckets = prometheus.DefBuckets
+	}
+	if options.FetchDurationBuckets == nil {
+		options.FetchDurationBuckets = prometheus.DefBuckets
+	}
+
+	m := &Metrics{
+		tasksFetched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "tasks_fetched_total",
+			Help:      "Number of fetched and locked external tasks.",
+		}, []string{"topic"}),
+		tasksHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "tasks_handled_total",
+			Help:      "Number of handled external tasks by outcome: complete, bpmn_error, failure, panic or none.",
+		}, []string{"topic", "outcome"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: options.Namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Duration of external task handling.",
+			Buckets:   options.HandlerDurationBuckets,
+		}, []string{"topic", "outcome"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: options.Namespace,
+			Name:      "tasks_in_flight",
+			Help:      "Number of running tasks of the topic.",
+		}, []string{"topic"}),
+		capacity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: options.Namespace,
+			Name:      "tasks_capacity",
+			Help:      "Maximum number of parallel tasks of the handler of the topic.",
+		}, []string{"topic"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: options.Namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "Duration of fetch-and-lock requests including long polling.",
+			Buckets:   options.FetchDurationBuckets,
+		}, []string{"topic"}),
+		fetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "fetch_errors_total",
+			Help:      "Number of failed fetch-and-lock requests.",
+		}, []string{"topic"}),
+		emptyPolls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: options.Namespace,
+			Name:      "empty_polls_total",
+			Help:      "Number of fetch-and-lock requests without tasks of the topic.",
+		}, []string{"topic"}),
+		backoff: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: options.Namespace,
+			Name:      "backoff_seconds",
+			Help:      "Current sleep of the puller after a failed fetch, 0 if the puller is not backing off.",
+		}, []string{"topic"}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.tasksFetched, m.tasksHandled, m.handlerDuration, m.inFlight, m.capacity,
+		m.fetchDuration, m.fetchErrors, m.emptyPolls, m.backoff,
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// FetchFinished collects fetched tasks, fetch latency, fetch errors and empty polls
+func (m *Metrics) FetchFinished(topic string, count int, duration time.Duration, err error) {
+	m.fetchDuration.WithLabelValues(topic).Observe(duration.Seconds())
+	if err != nil {
+		m.fetchErrors.WithLabelValues(topic).Inc()
+		return
+	}
+
+	if count == 0 {
+		m.emptyPolls.WithLabelValues(topic).Inc()
+		return
+	}
+
+	m.tasksFetched.WithLabelValues(topic).Add(float64(count))
+}
+
+// BackoffChanged collects the backoff state of the puller
+func (m *Metrics) BackoffChanged(topic string, duration time.Duration) {
+	m.backoff.WithLabelValues(topic).Set(duration.Seconds())
+}
+
+// TaskStarted collects running tasks. The in-flight gauge is incremented, snapshots of concurrent workers
+// may arrive out of order
+func (m *Metrics) TaskStarted(topic string, _, capacity int) {
+	m.inFlight.WithLabelValues(topic).Inc()
+	m.capacity.WithLabelValues(topic).Set(float64(capacity))
+}
+
+// TaskFinished collects handled tasks by outcome and handler duration
+func (m *Metrics) TaskFinished(topic string, outcome processor.Outcome, duration time.Duration, _, capacity int) {
+	m.tasksHandled.WithLabelValues(topic, string(outcome)).Inc()
+	m.handlerDuration.WithLabelValues(topic, string(outcome)).Observe(duration.Seconds())
+	m.inFlight.WithLabelValues(topic).Dec()
+	m.capacity.WithLabelValues(topic).Set(float64(capacity))
+}
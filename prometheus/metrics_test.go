@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/wurenquyu/camunda-client-go/v3/processor"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := NewMetrics(registry, Options{})
+	assert.NoError(t, err)
+
+	var _ processor.Metrics = m
+
+	m.FetchFinished("PrintHello", 3, time.Second, nil)
+	m.FetchFinished("PrintHello", 0, time.Second, nil)
+	m.FetchFinished("PrintHello", 0, time.Second, errors.New("connection refused"))
+	m.BackoffChanged("PrintHello", 2*time.Second)
+	m.TaskStarted("PrintHello", 1, 10)
+	m.TaskFinished("PrintHello", processor.OutcomeComplete, time.Second, 0, 10)
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.tasksFetched.WithLabelValues("PrintHello")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.emptyPolls.WithLabelValues("PrintHello")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.fetchErrors.WithLabelValues("PrintHello")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.backoff.WithLabelValues("PrintHello")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tasksHandled.WithLabelValues("PrintHello", "complete")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.inFlight.WithLabelValues("PrintHello")))
+	assert.Equal(t, 10.0, testutil.ToFloat64(m.capacity.WithLabelValues("PrintHello")))
+
+	_, err = NewMetrics(registry, Options{})
+	assert.Error(t, err)
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	m, err := NewMetrics(prometheus.NewRegistry(), Options{})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// snapshots of concurrent workers don't change the gauge
+			m.TaskStarted("PrintHello", i, 100)
+			m.TaskFinished("PrintHello", processor.OutcomeComplete, time.Millisecond, 100-i, 100)
+		}(i)
+	}
+	m.TaskStarted("PrintHello", 0, 100)
+	wg.Wait()
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.inFlight.WithLabelValues("PrintHello")))
+}
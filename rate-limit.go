@@ -0,0 +1,125 @@
+package camunda_client_go
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit a token bucket limit of requests
+type RateLimit struct {
+	// Mandatory. Requests per second
+	Rate float64
+	// maximum requests sent at once after an idle period (default: 1)
+	Burst int
+}
+
+// RateLimitOptions options for NewRateLimitInterceptor
+type RateLimitOptions struct {
+	// limit of all requests
+	Global *RateLimit
+	// limits per operation, e.g. `ProcessDefinition.StartInstance`, they are applied together with Global
+	Operations map[string]RateLimit
+}
+
+// NewRateLimitInterceptor returns an interceptor which delays requests exceeding the limits.
+// A request waits for a token until its context is done
+func NewRateLimitInterceptor(options RateLimitOptions) Interceptor {
+	var global *tokenBucket
+	if options.Global != nil {
+		global = newTokenBucket(*options.Global)
+	}
+
+	operations := make(map[string]*tokenBucket, len(options.Operations))
+	for operation, limit := range options.Operations {
+		operations[operation] = newTokenBucket(limit)
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *Request) (*http.Response, error) {
+			if bucket, ok := operations[req.Operation]; ok {
+				if err := bucket.wait(req); err != nil {
+					return nil, err
+				}
+			}
+
+			if global != nil {
+				if err := global.wait(req); err != nil {
+					return nil, err
+				}
+			}
+
+			return next(req)
+		}
+	}
+}
+
+// tokenBucket a token bucket rate limiter
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu       sync.Mutex
+	tokens   float64
+	updateAt time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucket{
+		rate:     limit.Rate,
+		burst:    burst,
+		tokens:   burst,
+		updateAt: time.Now(),
+	}
+}
+
+// reserve takes a token and returns the time to wait until the token is available
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.updateAt).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.updateAt = now
+
+	b.tokens--
+	if b.tokens >= 0 || b.rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns the reserved token
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens++
+}
+
+func (b *tokenBucket) wait(req *Request) error {
+	delay := b.reserve()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context.Done():
+		b.cancel()
+		return req.Context.Err()
+	}
+}
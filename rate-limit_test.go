@@ -0,0 +1,43 @@
+package camunda_client_go
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientOptions{
+		EndpointUrl: server.URL,
+		OperationRateLimits: map[string]RateLimit{
+			"Message.SendMessage": {Rate: 20, Burst: 2},
+		},
+	})
+
+	startedAt := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, client.Message.SendMessage(&ReqMessage{MessageName: "message"}))
+	}
+	// 2 requests of the burst and 2 requests with 50ms delay
+	assert.True(t, time.Since(startedAt) >= 90*time.Millisecond)
+
+	// other operations are not limited
+	startedAt = time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, client.ExternalTask.Unlock("task-id"))
+	}
+	assert.True(t, time.Since(startedAt) < 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, client.WithContext(ctx).Message.SendMessage(&ReqMessage{MessageName: "message"}), context.Canceled)
+}
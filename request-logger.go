@@ -0,0 +1,134 @@
+package camunda_client_go
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RedactedValue replaces values of secret fields in logged request and response bodies
+const RedactedValue = "[REDACTED]"
+
+// maxLoggedBodySize a maximum size of logged bodies in bytes
+const maxLoggedBodySize = 4096
+
+// DefaultRedactedFields names of JSON fields whose values are never logged, the match is case-insensitive
+// and by substring, e.g. `password` matches `userPassword`
+var DefaultRedactedFields = []string{"password", "secret", "token", "authorization", "credential"}
+
+// RequestLogOptions options for NewRequestLogInterceptor
+type RequestLogOptions struct {
+	// log request and response bodies, values of redacted fields are replaced by RedactedValue
+	LogBodies bool
+	// names of JSON fields to redact in addition to DefaultRedactedFields
+	RedactedFields []string
+}
+
+// NewRequestLogInterceptor returns an interceptor which logs every request at the debug level
+// and failed requests at the warn level. Headers are not logged
+func NewRequestLogInterceptor(logger Logger, options RequestLogOptions) Interceptor {
+	redacted := append(append([]string{}, DefaultRedactedFields...), options.RedactedFields...)
+	for i, field := range redacted {
+		redacted[i] = strings.ToLower(field)
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *Request) (*http.Response, error) {
+			fields := []interface{}{"operation", req.Operation, "method", req.Method, "path", req.Path}
+			if options.LogBodies && req.Body != nil {
+				fields = append(fields, "requestBody", redactBody(req.Header.Get("Content-Type"), req.Body, redacted))
+			}
+
+			startedAt := time.Now()
+			res, err := next(req)
+			fields = append(fields, "duration", time.Since(startedAt))
+			if err != nil {
+				logger.Warn("camunda request failed", append(fields, "error", err)...)
+				return res, err
+			}
+
+			fields = append(fields, "status", res.StatusCode)
+			if options.LogBodies && res.Body != nil {
+				body, readErr := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				res.Body = ioutil.NopCloser(bytes.NewReader(body))
+				if readErr != nil {
+					return res, readErr
+				}
+				fields = append(fields, "responseBody", redactBody(res.Header.Get("Content-Type"), body, redacted))
+			}
+
+			if res.StatusCode >= 400 {
+				logger.Warn("camunda request returned error status", fields...)
+			} else {
+				logger.Debug("camunda request", fields...)
+			}
+
+			return res, nil
+		}
+	}
+}
+
+// redactBody returns a printable body with redacted secret values of JSON fields
+func redactBody(contentType string, body []byte, redacted []string) string {
+	if !strings.HasPrefix(contentType, "application/json") {
+		if strings.HasPrefix(contentType, "text/") {
+			return truncateBody(string(body))
+		}
+
+		return "[" + strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]) + " body]"
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "[invalid JSON body]"
+	}
+
+	data, err := json.Marshal(redactValue(v, redacted))
+	if err != nil {
+		return "[invalid JSON body]"
+	}
+
+	return truncateBody(string(data))
+}
+
+func redactValue(v interface{}, redacted []string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if isRedactedField(key, redacted) {
+				value[key] = RedactedValue
+			} else {
+				value[key] = redactValue(item, redacted)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item, redacted)
+		}
+	}
+
+	return v
+}
+
+func isRedactedField(key string, redacted []string) bool {
+	key = strings.ToLower(key)
+	for _, field := range redacted {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func truncateBody(body string) string {
+	if len(body) > maxLoggedBodySize {
+		return body[:maxLoggedBodySize] + "..."
+	}
+
+	return body
+}
@@ -0,0 +1,47 @@
+package camunda_client_go
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogRedactsSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"instance-id","apiToken":"response-secret"}`))
+	}))
+	defer server.Close()
+
+	output := new(bytes.Buffer)
+	client := NewClient(ClientOptions{
+		EndpointUrl: server.URL,
+		ApiUser:     "demo",
+		ApiPassword: "basic-secret",
+		Logger:      NewStdLogger(log.New(output, "", 0), LogLevelDebug),
+		LogBodies:   true,
+	})
+
+	key := "demo"
+	res, err := client.ProcessDefinition.StartInstance(QueryProcessDefinitionBy{Key: &key}, ReqStartInstance{
+		Variables: &map[string]Variable{
+			"userPassword": {Value: "variable-secret", Type: "String"},
+			"amount":       {Value: 100, Type: "Integer"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "instance-id", res.Id)
+
+	logged := output.String()
+	assert.Contains(t, logged, "DEBUG camunda request operation=ProcessDefinition.StartInstance method=POST")
+	assert.Contains(t, logged, "status=200")
+	assert.Contains(t, logged, `"amount"`)
+	assert.Contains(t, logged, RedactedValue)
+	assert.NotContains(t, logged, "variable-secret")
+	assert.NotContains(t, logged, "response-secret")
+	assert.NotContains(t, logged, "basic-secret")
+}
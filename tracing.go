@@ -0,0 +1,72 @@
+package camunda_client_go
+
+import "context"
+
+// Attribute keys of spans created by the client and the external task processor
+const (
+	AttributeOperation         = "camunda.operation"
+	AttributeEnginePath        = "camunda.engine.path"
+	AttributeHttpMethod        = "http.method"
+	AttributeHttpStatusCode    = "http.status_code"
+	AttributeErrorType         = "camunda.error.type"
+	AttributeWorkerId          = "camunda.worker.id"
+	AttributeTopicName         = "camunda.topic.name"
+	AttributeTopicNames        = "camunda.topic.names"
+	AttributeTasksCount        = "camunda.tasks.count"
+	AttributeTaskId            = "camunda.task.id"
+	AttributeProcessInstanceId = "camunda.process_instance.id"
+	AttributeBusinessKey       = "camunda.business_key"
+	AttributeTaskRetries       = "camunda.task.retries"
+	AttributeTaskOutcome       = "camunda.task.outcome"
+)
+
+// SpanKind a role of a span in a trace
+type SpanKind int
+
+const (
+	// SpanKindInternal an internal operation, e.g. a step of a handler
+	SpanKindInternal SpanKind = iota
+	// SpanKindClient a request to the engine
+	SpanKindClient
+	// SpanKindConsumer handling of an external task fetched from the engine
+	SpanKindConsumer
+)
+
+// Tracer creates spans for requests to the engine and for external task handling.
+// An OpenTelemetry implementation is in the module github.com/wurenquyu/camunda-client-go/otel
+type Tracer interface {
+	// Start starts a new span of the kind as a child of the span in ctx and returns the context with the new span
+	Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span)
+}
+
+// Span a single traced operation
+type Span interface {
+	// SetAttributes sets attributes of the span
+	SetAttributes(attributes ...Attribute)
+	// RecordError marks the span as failed by the error
+	RecordError(err error)
+	// End completes the span
+	End()
+}
+
+// Attribute a key-value attribute of a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// NoopTracer a tracer which records nothing. It is used when no tracer is configured
+type NoopTracer struct{}
+
+// Start returns ctx and a span which records nothing
+func (NoopTracer) Start(ctx context.Context, _ string, _ SpanKind) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) RecordError(error) {}
+
+func (noopSpan) End() {}
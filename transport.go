@@ -0,0 +1,230 @@
+package camunda_client_go
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// TLSOptions TLS configuration of connections to the engine
+type TLSOptions struct {
+	// PEM file with CA certificates to verify the engine certificate (default: system roots)
+	CAFile string
+	// PEM encoded CA certificates, they are used together with CAFile
+	CAPem []byte
+	// PEM files with the client certificate and its private key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// server name to verify the engine certificate (default: host of EndpointUrl)
+	ServerName string
+	// minimum TLS version (default: tls.VersionTLS12)
+	MinVersion uint16
+	// disable verification of the engine certificate, use only for testing
+	InsecureSkipVerify bool
+	// interval to check CAFile, CertFile and KeyFile for changes, changed files are reloaded
+	// without restart (default: 0, files are loaded once)
+	ReloadInterval time.Duration
+}
+
+// transportOptions options of the http transport of the client
+type transportOptions struct {
+	tls                 *TLSOptions
+	proxy               func(*http.Request) (*url.URL, error)
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+// newTransport returns a http transport configured by options
+func newTransport(options transportOptions) http.RoundTripper {
+	if options.proxy == nil {
+		options.proxy = http.ProxyFromEnvironment
+	}
+	if options.maxIdleConns <= 0 {
+		options.maxIdleConns = DefaultMaxIdleConns
+	}
+	if options.maxIdleConnsPerHost <= 0 {
+		options.maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if options.idleConnTimeout <= 0 {
+		options.idleConnTimeout = DefaultIdleConnTimeout
+	}
+
+	if options.tls == nil {
+		return buildTransport(options, nil)
+	}
+
+	return &reloadingTransport{options: options}
+}
+
+func buildTransport(options transportOptions, tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: options.proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          options.maxIdleConns,
+		MaxIdleConnsPerHost:   options.maxIdleConnsPerHost,
+		IdleConnTimeout:       options.idleConnTimeout,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// reloadingTransport a transport with TLS configuration loaded from files.
+// If the files change, new connections use a new transport and idle connections of the old one are closed
+type reloadingTransport struct {
+	options transportOptions
+
+	mu        sync.Mutex
+	transport *http.Transport
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+// RoundTrip executes the request by the transport with the actual TLS configuration
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.RoundTrip(req)
+}
+
+// CloseIdleConnections closes idle connections of the actual transport
+func (t *reloadingTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+}
+
+func (t *reloadingTransport) current() (*http.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.transport != nil {
+		interval := t.options.tls.ReloadInterval
+		if interval <= 0 || time.Since(t.checkedAt) < interval {
+			return t.transport, nil
+		}
+
+		t.checkedAt = time.Now()
+		if !t.filesChanged() {
+			return t.transport, nil
+		}
+	}
+
+	tlsConfig, modTimes, err := loadTLSConfig(t.options.tls)
+	if err != nil {
+		if t.transport != nil {
+			// keep the previous configuration while files are being replaced
+			return t.transport, nil
+		}
+
+		return nil, err
+	}
+
+	if t.transport != nil {
+		t.transport.CloseIdleConnections()
+	}
+	t.transport = buildTransport(t.options, tlsConfig)
+	t.modTimes = modTimes
+	t.checkedAt = time.Now()
+
+	return t.transport, nil
+}
+
+func (t *reloadingTransport) filesChanged() bool {
+	for name, modTime := range t.modTimes {
+		info, err := os.Stat(name)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loadTLSConfig returns the TLS configuration and modification times of loaded files
+func loadTLSConfig(options *TLSOptions) (*tls.Config, map[string]time.Time, error) {
+	config := &tls.Config{
+		ServerName: options.ServerName,
+		MinVersion: options.MinVersion,
+		// #nosec G402 Insecure verification is enabled only explicitly
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+
+	modTimes := map[string]time.Time{}
+	readFile := func(name string) ([]byte, error) {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[name] = info.ModTime()
+
+		// #nosec G304 Certificate and key files are set by the configuration
+		return ioutil.ReadFile(name)
+	}
+
+	if options.CAFile != "" || len(options.CAPem) > 0 {
+		pool := x509.NewCertPool()
+		if len(options.CAPem) > 0 && !pool.AppendCertsFromPEM(options.CAPem) {
+			return nil, nil, errors.New("failed parse CA certificates from CAPem")
+		}
+
+		if options.CAFile != "" {
+			caPem, err := readFile(options.CAFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed read CA file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(caPem) {
+				return nil, nil, fmt.Errorf("failed parse CA certificates from %s", options.CAFile)
+			}
+		}
+
+		config.RootCAs = pool
+	}
+
+	if options.CertFile != "" || options.KeyFile != "" {
+		certPem, err := readFile(options.CertFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed read certificate file: %w", err)
+		}
+		keyPem, err := readFile(options.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed read key file: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, modTimes, nil
+}